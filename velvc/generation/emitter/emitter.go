@@ -1,7 +1,9 @@
 package emitter
 
 import (
+	"encoding/binary"
 	"fmt"
+	"math"
 	"os"
 	"reflect"
 )
@@ -21,6 +23,25 @@ const (
 	Rot
 	Set
 	Jump
+	Add
+	Sub
+	Mul
+	Div
+	Pow
+	Log
+	Neg
+	Eq
+	Neq
+	Lt
+	Gt
+	Lte
+	Gte
+	Not
+	And
+	Or
+	Xor
+	Enter
+	Local
 )
 
 /*
@@ -39,11 +60,30 @@ func (o Opcode) Name() string {
 		"Rot",
 		"Set",
 		"Jump",
+		"Add",
+		"Sub",
+		"Mul",
+		"Div",
+		"Pow",
+		"Log",
+		"Neg",
+		"Eq",
+		"Neq",
+		"Lt",
+		"Gt",
+		"Lte",
+		"Gte",
+		"Not",
+		"And",
+		"Or",
+		"Xor",
+		"Enter",
+		"Local",
 	}[o]
 }
 
 func writeFile(filename string, data []byte) error {
-	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE, 0o644)
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
 	if err != nil {
 		return err
 	}
@@ -64,6 +104,12 @@ type asmFlags struct {
 	isLibrary, f2, f3, f4, f5, f6, f7, f8 bool
 }
 
+// an instruction whose arguments are the address of a label that might not exist yet
+type labelRef struct {
+	instruction int
+	label       string
+}
+
 /*
 Generates the bytecode for the cvelv (Velvet VM Executable) format
 */
@@ -73,6 +119,7 @@ type VelvEmitter struct {
 	programEntry uint32
 	instructions [][7]byte
 	labels       map[string]uint32
+	labelRefs    []labelRef
 	staticCache  map[any][2]uint16
 	data         []byte
 }
@@ -92,6 +139,13 @@ func (va VelvEmitter) flagsToUint8() uint8 {
 Writes the completed bytecode to the given path
 */
 func (va VelvEmitter) Write(filename string) error {
+	return writeFile(filename, va.Bytes())
+}
+
+/*
+Returns the completed bytecode
+*/
+func (va VelvEmitter) Bytes() []byte {
 	output := []byte("Velvet Scarlatina")
 
 	output = append(output, va.flagsToUint8())
@@ -110,7 +164,7 @@ func (va VelvEmitter) Write(filename string) error {
 	}
 	output = append(output, va.data...)
 
-	return writeFile(filename, output)
+	return output
 }
 
 /*
@@ -140,6 +194,32 @@ func (va *VelvEmitter) AddNumber(value uint32) (uint16, uint16) {
 	return addr, 4
 }
 
+/*
+Appends a signed 64-bit integer to the data section
+*/
+func (va *VelvEmitter) AddInt64(value int64) (uint16, uint16) {
+	if pos, ok := va.staticCache[value]; ok {
+		return pos[0], pos[1]
+	}
+	addr := uint16(len(va.data))
+	va.data = binary.BigEndian.AppendUint64(va.data, uint64(value))
+	va.staticCache[value] = [2]uint16{addr, 8}
+	return addr, 8
+}
+
+/*
+Appends a 64-bit float to the data section
+*/
+func (va *VelvEmitter) AddFloat64(value float64) (uint16, uint16) {
+	if pos, ok := va.staticCache[value]; ok {
+		return pos[0], pos[1]
+	}
+	addr := uint16(len(va.data))
+	va.data = binary.BigEndian.AppendUint64(va.data, math.Float64bits(value))
+	va.staticCache[value] = [2]uint16{addr, 8}
+	return addr, 8
+}
+
 /*
 Appends a boolean to the data section
 */
@@ -179,15 +259,63 @@ func (va *VelvEmitter) AddString(value string) (uint16, uint16) {
 	return addr, uint16(len(value))
 }
 
+/*
+Map is the entries of a map literal, which are the keys and values one after the other
+*/
+type Map []any
+
+// the key lists and maps are cached with, a separate type so that it can never equal a cached string
+type collectionKey struct {
+	kind byte // 'l' for lists and 'm' for maps
+	repr string
+}
+
 /*
 Appends a list to the data section
 */
 func (va *VelvEmitter) AddList(values ...any) (uint16, uint16) {
-	// fmt.Println(fmt.Sprintf("%v", values))
-	if pos, ok := va.staticCache[fmt.Sprintf("%v", values)]; ok {
+	key := collectionKey{kind: 'l', repr: fmt.Sprintf("%#v", values)}
+	if pos, ok := va.staticCache[key]; ok {
+		return pos[0], pos[1]
+	}
+
+	// the items are added first, since they can add their own values to the data section
+	items := va.addItems(values)
+	addr := uint16(len(va.data))
+	va.data = append(va.data, items...)
+
+	va.staticCache[key] = [2]uint16{addr, uint16(len(values))}
+
+	return addr, uint16(len(values))
+}
+
+/*
+Appends a map to the data section, the entries are stored like a list of the keys and values and the length is the amount of entries
+*/
+func (va *VelvEmitter) AddMap(entries ...any) (uint16, uint16) {
+	if len(entries)%2 != 0 {
+		panic("a map needs a value for every key")
+	}
+
+	key := collectionKey{kind: 'm', repr: fmt.Sprintf("%#v", Map(entries))}
+	if pos, ok := va.staticCache[key]; ok {
 		return pos[0], pos[1]
 	}
 
+	// the items are added first, since they can add their own values to the data section
+	items := va.addItems(entries)
+	addr := uint16(len(va.data))
+	va.data = append(va.data, items...)
+
+	va.staticCache[key] = [2]uint16{addr, uint16(len(entries) / 2)}
+
+	return addr, uint16(len(entries) / 2)
+}
+
+/*
+Appends the values of the items to the data section, and returns the type, address and length of each item
+*/
+func (va *VelvEmitter) addItems(values []any) []byte {
 	spl16 := func(n uint16) []byte {
 		return []byte{byte(n >> 8), byte(n)}
 	}
@@ -197,11 +325,23 @@ func (va *VelvEmitter) AddList(values ...any) (uint16, uint16) {
 	for _, val := range values {
 		switch v := val.(type) {
 		case int:
-			{
+			if v >= math.MinInt32 && v <= math.MaxInt32 {
 				valAddr, valLen := va.AddNumber(uint32(v))
 				addedBytes = append(addedBytes, 0b0)
 				addedBytes = append(addedBytes, spl16(valAddr)...)
 				addedBytes = append(addedBytes, spl16(valLen)...)
+			} else {
+				valAddr, valLen := va.AddInt64(int64(v))
+				addedBytes = append(addedBytes, 0b10000)
+				addedBytes = append(addedBytes, spl16(valAddr)...)
+				addedBytes = append(addedBytes, spl16(valLen)...)
+			}
+		case float64:
+			{
+				valAddr, valLen := va.AddFloat64(v)
+				addedBytes = append(addedBytes, 0b100000)
+				addedBytes = append(addedBytes, spl16(valAddr)...)
+				addedBytes = append(addedBytes, spl16(valLen)...)
 			}
 		case string:
 			{
@@ -219,23 +359,26 @@ func (va *VelvEmitter) AddList(values ...any) (uint16, uint16) {
 			}
 		case []any:
 			{
-				valAddr, valLen := va.AddList(v)
+				valAddr, valLen := va.AddList(v...)
 				addedBytes = append(addedBytes, 0b100)
 				addedBytes = append(addedBytes, spl16(valAddr)...)
 				addedBytes = append(addedBytes, spl16(valLen)...)
 			}
+		case Map:
+			{
+				valAddr, valLen := va.AddMap(v...)
+				addedBytes = append(addedBytes, 0b10000000)
+				addedBytes = append(addedBytes, spl16(valAddr)...)
+				addedBytes = append(addedBytes, spl16(valLen)...)
+			}
+		case nil:
+			addedBytes = append(addedBytes, 0b1000000, 0, 0, 0, 0)
 		default:
 			panic(fmt.Sprintf("'%s' is not a valid type", reflect.TypeOf(val).Name()))
 		}
 	}
 
-	addr := uint16(len(va.data))
-
-	va.data = append(va.data, addedBytes...)
-
-	va.staticCache[fmt.Sprintf("%v", values)] = [2]uint16{addr, uint16(len(values))}
-
-	return addr, uint16(len(values))
+	return addedBytes
 }
 
 /*
@@ -267,6 +410,33 @@ func (va VelvEmitter) GetLabel(name string) uint32 {
 	}
 }
 
+/*
+Emits the instruction bytes of a generic instruction that uses the address of a label as its argument,
+the label doesn't need to exist until ResolveLabels is called
+*/
+func (va *VelvEmitter) EmitLabel(op Opcode, flag uint8, label string) {
+	va.labelRefs = append(va.labelRefs, labelRef{instruction: len(va.instructions), label: label})
+	va.Emit32(op, flag, 0)
+}
+
+/*
+Fills in the label addresses of the instructions emitted with EmitLabel
+*/
+func (va *VelvEmitter) ResolveLabels() error {
+	for _, ref := range va.labelRefs {
+		addr, ok := va.labels[ref.label]
+		if !ok {
+			return fmt.Errorf("label '%s' does not exist", ref.label)
+		}
+
+		ins := &va.instructions[ref.instruction]
+		ins[3], ins[4], ins[5], ins[6] = uint8(addr>>24), uint8(addr>>16), uint8(addr>>8), uint8(addr)
+	}
+
+	va.labelRefs = []labelRef{}
+	return nil
+}
+
 /*
 Emits the instruction bytes of a generic instruction that uses the two argument shorts separately
 */
@@ -297,6 +467,14 @@ func (va *VelvEmitter) EmitList(op Opcode, flag uint8, values ...any) {
 	va.Emit(op, flag, addr, length)
 }
 
+/*
+Emits the instruction bytes of a generic instruction that uses a map
+*/
+func (va *VelvEmitter) EmitMap(op Opcode, flag uint8, entries ...any) {
+	addr, length := va.AddMap(entries...)
+	va.Emit(op, flag, addr, length)
+}
+
 /*
 Emits the instruction bytes of a generic instruction that doesn't have a flag and uses the two argument shorts separately
 */
@@ -0,0 +1,38 @@
+package emitter_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/voidwyrm-2/velvet-vm/velvc/generation/emitter"
+	"github.com/voidwyrm-2/velvet-vm/velvet/vm"
+)
+
+func TestLiteralsDontShareCachedStrings(t *testing.T) {
+	list, entries := []any{1}, []any{"a", 1}
+
+	// strings that look like how the list and map literals are cached
+	listLike, mapLike := fmt.Sprintf("%#v", list), fmt.Sprintf("%#v", emitter.Map(entries))
+
+	ve := emitter.New(0)
+	ve.EmitList(emitter.Push, 3, list...)
+	ve.EmitString(emitter.Call, 0, "println")
+	ve.EmitMap(emitter.Push, 10, entries...)
+	ve.EmitString(emitter.Call, 0, "println")
+	ve.EmitString(emitter.Push, 2, listLike)
+	ve.EmitString(emitter.Call, 0, "println")
+	ve.EmitString(emitter.Push, 2, mapLike)
+	ve.EmitString(emitter.Call, 0, "println")
+	ve.Halt(0)
+
+	var out strings.Builder
+	if _, err := vm.New(vm.WithStdout(&out)).Run(ve.Bytes(), false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := fmt.Sprintf("[ 1 ]\n{ \"a\" 1 }\n%s\n%s\n", listLike, mapLike)
+	if out.String() != expected {
+		t.Errorf("expected %q, but got %q", expected, out.String())
+	}
+}
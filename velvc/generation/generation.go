@@ -20,9 +20,13 @@ func (g *Generator) Generate() error {
 			return err
 		}
 	}
-	return nil
+	return g.ve.ResolveLabels()
 }
 
 func (g Generator) Write(filename string) error {
 	return g.ve.Write(filename)
 }
+
+func (g Generator) Bytes() []byte {
+	return g.ve.Bytes()
+}
@@ -80,7 +80,7 @@ func (l *Lexer) collectNumber(kind uint8) (tokens.Token, error) {
 	startln := l.ln
 	s := ""
 
-	if kind == 2 {
+	if kind == 1 || kind == 2 {
 		l.advance()
 	}
 
@@ -90,6 +90,19 @@ func (l *Lexer) collectNumber(kind uint8) (tokens.Token, error) {
 	}
 
 	tkind := tokens.Number
+
+	// a fractional part makes the number a float
+	if kind != 2 && l.ch == '.' && isNum(l.peek()) {
+		tkind = tokens.Float
+		s += "."
+		l.advance()
+
+		for l.ch != -1 && (l.isNum() || l.ch == '_') {
+			s += string(l.ch)
+			l.advance()
+		}
+	}
+
 	switch kind {
 	case 0:
 	case 1:
@@ -145,6 +158,8 @@ func (l *Lexer) collectIdent(kind uint8) tokens.Token {
 
 	if s == "true" || s == "false" {
 		tkind = tokens.Bool
+	} else if s == "null" {
+		tkind = tokens.Null
 	}
 
 	return tokens.New(tkind, s, start, startln)
@@ -214,6 +229,12 @@ func (l *Lexer) Lex() ([]tokens.Token, error) {
 		case ']':
 			toks = append(toks, l.charTok(tokens.CloseBracket))
 			l.advance()
+		case '{':
+			toks = append(toks, l.charTok(tokens.OpenBrace))
+			l.advance()
+		case '}':
+			toks = append(toks, l.charTok(tokens.CloseBrace))
+			l.advance()
 		case '"':
 			if tok, err := l.collectString(); err != nil {
 				return []tokens.Token{}, err
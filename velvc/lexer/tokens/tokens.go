@@ -19,6 +19,10 @@ const (
 	Label
 	OpenBracket
 	CloseBracket
+	Float
+	Null
+	OpenBrace
+	CloseBrace
 )
 
 func (tt TokenType) Str() string {
@@ -33,6 +37,10 @@ func (tt TokenType) Str() string {
 		"Label",
 		"OpenBracket",
 		"CloseBracket",
+		"Float",
+		"Null",
+		"OpenBrace",
+		"CloseBrace",
 	}[tt]
 }
 
@@ -81,6 +89,8 @@ func (t Token) Convert() (any, error) {
 	switch t.kind {
 	case Number, Address:
 		return strconv.Atoi(t.lit)
+	case Float:
+		return strconv.ParseFloat(t.lit, 64)
 	case String:
 		return t.lit, nil
 	case Bool:
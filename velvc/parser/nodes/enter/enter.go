@@ -0,0 +1,30 @@
+package enter
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/voidwyrm-2/velvet-vm/velvc/generation/emitter"
+	"github.com/voidwyrm-2/velvet-vm/velvc/lexer/tokens"
+)
+
+func assert[T any](v T, _ error) T {
+	return v
+}
+
+type EnterNode struct {
+	instruction, args, locals tokens.Token
+}
+
+func New(instruction, args, locals tokens.Token) EnterNode {
+	return EnterNode{instruction: instruction, args: args, locals: locals}
+}
+
+func (en EnterNode) Generate(ve *emitter.VelvEmitter) error {
+	ve.Emit(emitter.Enter, 0, uint16(assert(strconv.Atoi(en.args.GetLit()))), uint16(assert(strconv.Atoi(en.locals.GetLit()))))
+	return nil
+}
+
+func (en EnterNode) Str() string {
+	return fmt.Sprintf("{ins: %s, args: %s, locals: %s}", en.instruction.Str(), en.args.Str(), en.locals.Str())
+}
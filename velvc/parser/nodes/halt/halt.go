@@ -2,29 +2,25 @@ package halt
 
 import (
 	"fmt"
-	"strconv"
 
 	"github.com/voidwyrm-2/velvet-vm/velvc/generation/emitter"
 	"github.com/voidwyrm-2/velvet-vm/velvc/lexer/tokens"
 )
 
-func assert[T any](v T, _ error) T {
-	return v
-}
-
 type HaltNode struct {
-	instruction, exitCode tokens.Token
+	instruction tokens.Token
+	exitCode    int8
 }
 
-func New(instruction, exitCode tokens.Token) HaltNode {
+func New(instruction tokens.Token, exitCode int8) HaltNode {
 	return HaltNode{instruction: instruction, exitCode: exitCode}
 }
 
 func (hn HaltNode) Generate(ve *emitter.VelvEmitter) error {
-	ve.EmitNF32(emitter.Halt, uint32(assert(strconv.Atoi(hn.exitCode.GetLit()))))
+	ve.Halt(hn.exitCode)
 	return nil
 }
 
 func (hn HaltNode) Str() string {
-	return fmt.Sprintf("{ins: %s, exitCode: %s}", hn.instruction.Str(), hn.exitCode.Str())
+	return fmt.Sprintf("{ins: %s, exitCode: %d}", hn.instruction.Str(), hn.exitCode)
 }
@@ -30,7 +30,7 @@ func (jn JumpNode) Generate(ve *emitter.VelvEmitter) error {
 		panic(fmt.Sprintf("isBranch is %d instead of 0 or 1", isBranch))
 	}
 
-	ve.Emit32(emitter.Jump, map[string]uint8{"": 0, "t": 1, "f": 2, "e": 3, "ne": 4}[jumpType]<<(3*isBranch), ve.GetLabel(jn.label.GetLit()))
+	ve.EmitLabel(emitter.Jump, map[string]uint8{"": 0, "t": 1, "f": 2, "e": 3, "ne": 4}[jumpType]|uint8(isBranch)<<3, jn.label.GetLit())
 	return nil
 }
 
@@ -0,0 +1,43 @@
+package operator
+
+import (
+	"fmt"
+
+	"github.com/voidwyrm-2/velvet-vm/velvc/generation/emitter"
+	"github.com/voidwyrm-2/velvet-vm/velvc/lexer/tokens"
+)
+
+type OperatorNode struct {
+	instruction tokens.Token
+}
+
+func New(instruction tokens.Token) OperatorNode {
+	return OperatorNode{instruction: instruction}
+}
+
+func (on OperatorNode) Generate(ve *emitter.VelvEmitter) error {
+	ve.EmitBasic(map[string]emitter.Opcode{
+		"add": emitter.Add,
+		"sub": emitter.Sub,
+		"mul": emitter.Mul,
+		"div": emitter.Div,
+		"pow": emitter.Pow,
+		"log": emitter.Log,
+		"neg": emitter.Neg,
+		"eq":  emitter.Eq,
+		"neq": emitter.Neq,
+		"lt":  emitter.Lt,
+		"gt":  emitter.Gt,
+		"lte": emitter.Lte,
+		"gte": emitter.Gte,
+		"not": emitter.Not,
+		"and": emitter.And,
+		"or":  emitter.Or,
+		"xor": emitter.Xor,
+	}[on.instruction.GetLit()])
+	return nil
+}
+
+func (on OperatorNode) Str() string {
+	return fmt.Sprintf("{ins: %s}", on.instruction.Str())
+}
@@ -2,6 +2,7 @@ package pushcall
 
 import (
 	"fmt"
+	"math"
 	"strings"
 
 	"github.com/voidwyrm-2/velvet-vm/velvc/generation/emitter"
@@ -22,36 +23,91 @@ func New(instruction tokens.Token, args []tokens.Token) PushCallNode {
 	return PushCallNode{instruction: instruction, args: args, ins: 0}
 }
 
-func (pcn PushCallNode) GenerateList() ([]any, error) {
+/*
+Collects the items of the list that starts at the current argument, including any nested lists and maps,
+leaving the current argument after the list's closing bracket
+*/
+func (pcn *PushCallNode) GenerateList() ([]any, error) {
+	ls, _, err := pcn.generateItems(tokens.CloseBracket, "list")
+	return ls, err
+}
+
+/*
+Collects the keys and values of the map that starts at the current argument, including any nested lists and maps,
+leaving the current argument after the map's closing brace
+*/
+func (pcn *PushCallNode) GenerateMap() (emitter.Map, error) {
+	open := pcn.args[pcn.ins]
+
+	entries, starts, err := pcn.generateItems(tokens.CloseBrace, "map")
+	if err != nil {
+		return emitter.Map{}, err
+	}
+
+	for i := 0; i < len(entries); i += 2 {
+		switch entries[i].(type) {
+		case []any, emitter.Map:
+			return emitter.Map{}, starts[i].Err("a list or map cannot be a map key")
+		}
+	}
+
+	if len(entries)%2 != 0 {
+		return emitter.Map{}, open.Err("map has a key without a value")
+	}
+
+	return emitter.Map(entries), nil
+}
+
+/*
+Collects items until the given closing token, returning the items and the token each item starts at;
+the kind of collection is used in errors
+*/
+func (pcn *PushCallNode) generateItems(close tokens.TokenType, collection string) ([]any, []tokens.Token, error) {
 	ls := []any{}
+	starts := []tokens.Token{}
+	open := pcn.args[pcn.ins]
+	pcn.ins += 1
+
 	for pcn.ins < len(pcn.args) {
-		switch pcn.args[pcn.ins].GetKind() {
+		start := pcn.args[pcn.ins]
+
+		switch start.GetKind() {
 		case tokens.Number:
-			ls = append(ls, assert(pcn.args[pcn.ins].Convert()).(int))
-			/*case tokens.Address:
-			if anyX, err := pcn.args[pcn.ins].Convert(); err != nil {
-				return []any{},err
-			} else if anyY, err := pcn.args[pcn.ins].Convert(); err != nil {
-				return []any{},err
-			} else {
-				ve.Emit(emitter.Push, 0, uint16(anyX.(int)), uint16(anyY.(int)))
-			}*/
+			ls = append(ls, assert(start.Convert()).(int))
+		case tokens.Float:
+			ls = append(ls, assert(start.Convert()).(float64))
 		case tokens.Bool:
-			ls = append(ls, assert(pcn.args[pcn.ins].Convert()).(bool))
+			ls = append(ls, assert(start.Convert()).(bool))
+		case tokens.Null:
+			ls = append(ls, nil)
 		case tokens.String:
-			ls = append(ls, assert(pcn.args[pcn.ins].Convert()).(string))
+			ls = append(ls, assert(start.Convert()).(string))
 		case tokens.OpenBracket:
 			if subls, err := pcn.GenerateList(); err != nil {
-				return []any{}, err
+				return []any{}, []tokens.Token{}, err
 			} else {
 				ls = append(ls, subls)
 			}
-		case tokens.CloseBracket:
-			return ls, nil
+		case tokens.OpenBrace:
+			if subm, err := pcn.GenerateMap(); err != nil {
+				return []any{}, []tokens.Token{}, err
+			} else {
+				ls = append(ls, subm)
+			}
+		case close:
+			pcn.ins += 1
+			return ls, starts, nil
+		default:
+			return []any{}, []tokens.Token{}, start.Err("'%s' cannot be a %s item", start.GetLit(), collection)
+		}
+
+		starts = append(starts, start)
+		if !start.IsKind(tokens.OpenBracket) && !start.IsKind(tokens.OpenBrace) {
+			pcn.ins += 1
 		}
-		pcn.ins += 1
 	}
-	return ls, nil
+
+	return []any{}, []tokens.Token{}, open.Err("%s is never closed", collection)
 }
 
 func (pcn PushCallNode) Generate(ve *emitter.VelvEmitter) error {
@@ -68,22 +124,49 @@ func (pcn PushCallNode) Generate(ve *emitter.VelvEmitter) error {
 	} else {
 		switch pcn.args[pcn.ins].GetKind() {
 		case tokens.Number:
-			ve.Emit32(emitter.Push, 0, uint32(assert(pcn.args[pcn.ins].Convert()).(int)))
+			// numbers that don't fit in the instruction arguments are put in the data section
+			if n := assert(pcn.args[pcn.ins].Convert()).(int); n >= math.MinInt32 && n <= math.MaxInt32 {
+				ve.Emit32(emitter.Push, 0, uint32(n))
+			} else {
+				addr, length := ve.AddInt64(int64(n))
+				ve.Emit(emitter.Push, 7, addr, length)
+			}
+		case tokens.Float:
+			addr, length := ve.AddFloat64(assert(pcn.args[pcn.ins].Convert()).(float64))
+			ve.Emit(emitter.Push, 8, addr, length)
 		case tokens.Address:
 			ve.Emit(emitter.Push, 2, uint16(assert(pcn.args[pcn.ins].Convert()).(int)), uint16(assert(pcn.args[pcn.ins+1].Convert()).(int)))
 		case tokens.Bool:
-			ve.EmitString(emitter.Push, 1, assert(pcn.args[pcn.ins].Convert()).(string))
+			if assert(pcn.args[pcn.ins].Convert()).(bool) {
+				ve.Emit(emitter.Push, 1, 1, 0)
+			} else {
+				ve.Emit(emitter.Push, 1, 0, 0)
+			}
+		case tokens.Null:
+			ve.EmitNA(emitter.Push, 9)
 		case tokens.String:
 			ve.EmitString(emitter.Push, 2, assert(pcn.args[pcn.ins].Convert()).(string))
 		case tokens.Ident:
-			ve.EmitString(emitter.Push, 4, assert(pcn.args[pcn.ins].Convert()).(string))
+			ve.EmitString(emitter.Push, 4, pcn.args[pcn.ins].GetLit())
+		case tokens.Label:
+			ve.EmitLabel(emitter.Push, 6, pcn.args[pcn.ins].GetLit())
 
 		case tokens.OpenBracket:
 			if ls, err := pcn.GenerateList(); err != nil {
 				return err
+			} else if pcn.ins < len(pcn.args) {
+				return pcn.args[pcn.ins].Err("expected EOL, but found '%s' instead", pcn.args[pcn.ins].GetLit())
 			} else {
 				ve.EmitList(emitter.Push, 3, ls...)
 			}
+		case tokens.OpenBrace:
+			if m, err := pcn.GenerateMap(); err != nil {
+				return err
+			} else if pcn.ins < len(pcn.args) {
+				return pcn.args[pcn.ins].Err("expected EOL, but found '%s' instead", pcn.args[pcn.ins].GetLit())
+			} else {
+				ve.EmitMap(emitter.Push, 10, m...)
+			}
 		}
 	}
 	return nil
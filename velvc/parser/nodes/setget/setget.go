@@ -21,12 +21,17 @@ func New(instruction, varIndex tokens.Token) SetgetNode {
 }
 
 func (sn SetgetNode) Generate(ve *emitter.VelvEmitter) error {
-	var f uint8 = 0
-	if sn.instruction.GetLit() == "get" {
+	op, f := emitter.Set, uint8(0)
+	switch sn.instruction.GetLit() {
+	case "get":
 		f = 1
+	case "lset":
+		op = emitter.Local
+	case "lget":
+		op, f = emitter.Local, 1
 	}
 
-	ve.Emit32(emitter.Set, f, uint32(assert(strconv.Atoi(sn.varIndex.GetLit()))))
+	ve.Emit(op, f, uint16(assert(strconv.Atoi(sn.varIndex.GetLit()))), 0)
 
 	return nil
 }
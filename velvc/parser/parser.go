@@ -1,14 +1,16 @@
 package parser
 
 import (
-	"slices"
+	"strconv"
 
 	"github.com/voidwyrm-2/velvet-vm/velvc/lexer/tokens"
 	"github.com/voidwyrm-2/velvet-vm/velvc/parser/nodes"
 	"github.com/voidwyrm-2/velvet-vm/velvc/parser/nodes/directive"
+	"github.com/voidwyrm-2/velvet-vm/velvc/parser/nodes/enter"
 	"github.com/voidwyrm-2/velvet-vm/velvc/parser/nodes/halt"
 	"github.com/voidwyrm-2/velvet-vm/velvc/parser/nodes/jump"
 	"github.com/voidwyrm-2/velvet-vm/velvc/parser/nodes/label"
+	"github.com/voidwyrm-2/velvet-vm/velvc/parser/nodes/operator"
 	"github.com/voidwyrm-2/velvet-vm/velvc/parser/nodes/otherinstruction"
 	"github.com/voidwyrm-2/velvet-vm/velvc/parser/nodes/pushcall"
 	"github.com/voidwyrm-2/velvet-vm/velvc/parser/nodes/setget"
@@ -43,7 +45,7 @@ func New(tokenL [][]tokens.Token) Parser {
 		}
 	}
 
-	return Parser{tokenL: tokenL}
+	return Parser{tokenL: filtered}
 }
 
 func (p Parser) Parse() ([]nodes.Node, error) {
@@ -74,37 +76,49 @@ func (p Parser) Parse() ([]nodes.Node, error) {
 				if err := expect(l, tokens.Number); err != nil {
 					return []nodes.Node{}, err
 				}
-				ns = append(ns, halt.New(head, l[0]))
+
+				// exit codes are stored in a signed byte
+				code, err := strconv.ParseInt(l[0].GetLit(), 10, 8)
+				if err != nil {
+					return []nodes.Node{}, l[0].Err("exit code '%s' is not between -128 and 127", l[0].GetLit())
+				}
+				ns = append(ns, halt.New(head, int8(code)))
 				continue
-			case "push", "pusherr":
-				if err := expect(l, tokens.Number); false {
-				} else if err2 := expect(l, tokens.String); false {
-				} else if err3 := expect(l, tokens.Number); false {
-				} else if err4 := expect(l, tokens.Bool); false {
-				} else if err5A := expect(l, tokens.Address); false {
-				} else if err5B := expect(l, tokens.Address); false {
-				} else if err6A := expect(l, tokens.Bool); false {
-				} else {
-					newL := make([]tokens.Token, len(l))
-					copy(newL, l)
-					slices.Reverse(newL)
-					if err6B := expect(newL, tokens.Bool); false {
-					} else if err == nil || err2 == nil || err3 == nil || err4 == nil || (err5A == nil && err5B == nil) || (err6A == nil && err6B == nil) {
-					} else if err5A == nil && err5B != nil {
-						return []nodes.Node{}, err5B
-					} else if err6A == nil && err6B != nil {
-						return []nodes.Node{}, err6B
-					} else if err6A != nil && err6B == nil {
-						return []nodes.Node{}, err6A
-					} else {
+			case "pusherr":
+				if err := expect(l); err != nil {
+					return []nodes.Node{}, err
+				}
+				ns = append(ns, pushcall.New(head, l))
+				continue
+			case "push":
+				if len(l) == 0 {
+					return []nodes.Node{}, head.Err("expected a value to push, but found EOL instead")
+				}
+
+				switch l[0].GetKind() {
+				case tokens.Number, tokens.Float, tokens.String, tokens.Bool, tokens.Null, tokens.Ident, tokens.Label:
+					if err := expect(l, l[0].GetKind()); err != nil {
+						return []nodes.Node{}, err
+					}
+				case tokens.Address:
+					if err := expect(l, tokens.Address, tokens.Address); err != nil {
 						return []nodes.Node{}, err
 					}
+				case tokens.OpenBracket, tokens.OpenBrace:
+					// the items are checked when the list or map is generated
+				default:
+					return []nodes.Node{}, l[0].Err("cannot push '%s'", l[0].GetLit())
 				}
+
 				ns = append(ns, pushcall.New(head, l))
 				continue
-			case "error",
-				"reset",
-				"eq",
+			case "error", "reset":
+				if err := expect(l); err != nil {
+					return []nodes.Node{}, err
+				}
+				ns = append(ns, pushcall.New(tokens.NewLit(tokens.Ident, "call"), []tokens.Token{head}))
+				continue
+			case "eq",
 				"neq",
 				"not",
 				"lt",
@@ -124,10 +138,14 @@ func (p Parser) Parse() ([]nodes.Node, error) {
 				if err := expect(l); err != nil {
 					return []nodes.Node{}, err
 				}
-				ns = append(ns, pushcall.New(tokens.NewLit(tokens.Ident, "call"), []tokens.Token{head}))
+				ns = append(ns, operator.New(head))
 				continue
 			case "call":
-				if err := expect(l, tokens.Ident); false {
+				if len(l) == 0 {
+					// calls the function on top of the stack
+					ns = append(ns, pushcall.New(head, l))
+					continue
+				} else if err := expect(l, tokens.Ident); false {
 				} else if err2A := expect(l, tokens.Address); false {
 				} else if err2B := expect(l, tokens.Address, tokens.Address); err == nil || (err2A == nil && err2B == nil) {
 					ns = append(ns, pushcall.New(head, l))
@@ -137,12 +155,18 @@ func (p Parser) Parse() ([]nodes.Node, error) {
 				} else {
 					return []nodes.Node{}, err
 				}
-			case "set", "get":
+			case "set", "get", "lset", "lget":
 				if err := expect(l, tokens.Number); err != nil {
 					return []nodes.Node{}, err
 				}
 				ns = append(ns, setget.New(head, l[0]))
 				continue
+			case "enter":
+				if err := expect(l, tokens.Number, tokens.Number); err != nil {
+					return []nodes.Node{}, err
+				}
+				ns = append(ns, enter.New(head, l[0], l[1]))
+				continue
 			case "j", "jt", "jf", "je", "jne", "br", "brt", "brf", "bre", "brne":
 				if err := expect(l, tokens.Ident); err != nil {
 					return []nodes.Node{}, err
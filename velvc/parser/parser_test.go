@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/voidwyrm-2/velvet-vm/velvc/lexer"
+	"github.com/voidwyrm-2/velvet-vm/velvc/sectioner"
+)
+
+// Lexes and parses source, failing the test if it doesn't lex
+func parse(t *testing.T, source string) error {
+	t.Helper()
+
+	lex := lexer.New(source)
+	toks, err := lex.Lex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = New(sectioner.SectionIntoLines(toks)).Parse()
+	return err
+}
+
+func TestHaltExitCodes(t *testing.T) {
+	for _, code := range []string{"0", "127", "-128"} {
+		if err := parse(t, "halt "+code); err != nil {
+			t.Errorf("expected 'halt %s' to parse, but got %v", code, err)
+		}
+	}
+
+	for _, code := range []string{"128", "255", "-129", "99999999999999999999"} {
+		err := parse(t, "push 1\nhalt "+code)
+		if err == nil {
+			t.Errorf("expected 'halt %s' to be rejected", code)
+		} else if !strings.HasPrefix(err.Error(), "error on line 2, ") {
+			t.Errorf("expected the error for 'halt %s' to point at the exit code, but got %v", code, err)
+		}
+	}
+}
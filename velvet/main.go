@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io"
@@ -32,6 +33,8 @@ func main() {
 	dumpStackAtEnd := flag.Bool("show-end", false, "Dump the stack at the end of the program")
 	dumpVarsAfterEachInstruction := flag.Bool("showvars", false, "Print the vars after each instruction")
 	// dumpVarsAtEnd := flag.Bool("showvars-end", false, "Dump the vars at the end of the program")
+	fuel := flag.Int64("fuel", 0, "The maximum amount of instructions the program can execute, no limit if zero")
+	timeout := flag.Duration("timeout", 0, "How long the program can run for before it's stopped, no limit if zero")
 	showVersion := flag.Bool("v", false, "Show the current Velvet version")
 
 	flag.Parse()
@@ -53,17 +56,29 @@ func main() {
 		return
 	}
 
-	virmac := vm.New()
-	if err = virmac.Run(content, *dumpStackAfterEachInstruction, *dumpVarsAfterEachInstruction); err != nil {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if *timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+	}
+
+	virmac := vm.New(vm.WithFuel(*fuel))
+	res, err := virmac.RunContext(ctx, content, *dumpStackAfterEachInstruction, *dumpVarsAfterEachInstruction)
+
+	// os.Exit skips deferred calls, so the context is cancelled as soon as the run is over
+	cancel()
+
+	if err != nil {
 		fmt.Println(err.Error())
-		return
-	} else {
-		if *dumpStackAtEnd && !*dumpStackAfterEachInstruction {
-			fmt.Println(virmac.DumpStack())
-		}
-
-		/*if *dumpVarsAtEnd && !*dumpVarsAfterEachInstruction {
-			fmt.Println(virmac.DumpVars())
-		}*/
+		os.Exit(1)
+	}
+
+	if *dumpStackAtEnd && !*dumpStackAfterEachInstruction {
+		fmt.Println(res.Stack.Dump())
 	}
+
+	/*if *dumpVarsAtEnd && !*dumpVarsAfterEachInstruction {
+		fmt.Println(virmac.DumpVars())
+	}*/
+
+	os.Exit(res.ExitCode)
 }
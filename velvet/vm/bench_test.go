@@ -0,0 +1,71 @@
+package vm
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+)
+
+// the Fibonacci example without reading the amount of numbers to print
+const benchFib = `
+@vars 1
+
+push 30
+set 0
+
+push 0 // a
+push 1 // b
+
+.fibloop
+  swap
+  dup
+  call println
+  swap
+  dup
+  rot
+  add
+  swap
+
+  get 0
+  push 1
+  sub
+  dup
+  set 0
+
+  push 0
+  gt
+  jt fibloop
+
+halt 0
+`
+
+// Runs a program once per iteration on the same VM
+func benchmarkProgram(b *testing.B, source string) {
+	prog, err := Load(compile(b, source))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	virmac := New(WithStdout(io.Discard))
+	for range b.N {
+		if _, err := virmac.RunProgram(context.Background(), prog, false, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoop(b *testing.B) {
+	loop, err := os.ReadFile("../../examples/loop.velv")
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkProgram(b, string(loop))
+}
+
+func BenchmarkFib(b *testing.B) {
+	benchmarkProgram(b, benchFib)
+}
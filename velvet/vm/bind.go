@@ -0,0 +1,286 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+
+	"github.com/voidwyrm-2/velvet-vm/velvet/vm/stack"
+)
+
+var (
+	errorType      = reflect.TypeFor[error]()
+	stackValueType = reflect.TypeFor[stack.StackValue]()
+	stackFuncType  = reflect.TypeFor[func(st *stack.Stack) error]()
+)
+
+// returns the stack kind that values of the given Go type are converted from and to
+func kindOfType(t reflect.Type) (stack.ValueKind, error) {
+	if t == stackValueType {
+		return stack.Any, nil
+	} else if t == stackFuncType {
+		return stack.Function, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return stack.Number, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return stack.Int, nil
+	case reflect.String:
+		return stack.String, nil
+	case reflect.Bool:
+		return stack.Bool, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return stack.Bytes, nil
+		} else if _, err := kindOfType(t.Elem()); err != nil {
+			return 0, err
+		}
+		return stack.List, nil
+	case reflect.Map:
+		if kind, err := kindOfType(t.Key()); err != nil {
+			return 0, err
+		} else if kind&^stack.Key != 0 {
+			return 0, fmt.Errorf("type '%s' cannot be converted to a stack value, a %s can't be a map key", t, kind.Name())
+		} else if _, err := kindOfType(t.Elem()); err != nil {
+			return 0, err
+		}
+		return stack.Map, nil
+	}
+
+	return 0, fmt.Errorf("type '%s' cannot be converted to a stack value", t)
+}
+
+// converts a stack value to a Go value of the given type
+func fromStackValue(sv stack.StackValue, t reflect.Type) (reflect.Value, error) {
+	kind, err := kindOfType(t)
+	if err != nil {
+		return reflect.Value{}, err
+	} else if !sv.Is(kind) {
+		return reflect.Value{}, &stack.ExpectError{Expected: kind, Actual: sv.GetKind()}
+	}
+
+	if t == stackValueType {
+		return reflect.ValueOf(sv), nil
+	} else if t == stackFuncType {
+		return reflect.ValueOf(sv.GetFunc()), nil
+	}
+
+	v := reflect.New(t).Elem()
+
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(sv.GetFloat())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.OverflowInt(sv.GetInt()) {
+			return reflect.Value{}, fmt.Errorf("%d doesn't fit in '%s'", sv.GetInt(), t)
+		}
+		v.SetInt(sv.GetInt())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if sv.GetInt() < 0 || v.OverflowUint(uint64(sv.GetInt())) {
+			return reflect.Value{}, fmt.Errorf("%d doesn't fit in '%s'", sv.GetInt(), t)
+		}
+		v.SetUint(uint64(sv.GetInt()))
+	case reflect.String:
+		v.SetString(sv.GetString())
+	case reflect.Bool:
+		v.SetBool(sv.GetBool())
+	case reflect.Slice:
+		if sv.Is(stack.Bytes) {
+			v.SetBytes(slices.Clone(sv.GetBytes()))
+			break
+		}
+
+		items := sv.GetList()
+		v.Set(reflect.MakeSlice(t, len(items), len(items)))
+		for i, item := range items {
+			if iv, err := fromStackValue(item, t.Elem()); err != nil {
+				return reflect.Value{}, err
+			} else {
+				v.Index(i).Set(iv)
+			}
+		}
+	case reflect.Map:
+		v.Set(reflect.MakeMapWithSize(t, sv.GetMap().Len()))
+
+		var err error
+		sv.GetMap().Range(func(key, value stack.StackValue) bool {
+			var kv, vv reflect.Value
+			if kv, err = fromStackValue(key, t.Key()); err != nil {
+				return false
+			} else if vv, err = fromStackValue(value, t.Elem()); err != nil {
+				return false
+			}
+			v.SetMapIndex(kv, vv)
+			return true
+		})
+		if err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
+	return v, nil
+}
+
+// converts a Go value to a stack value, unsigned integers that don't fit in an Int are an error
+func toStackValue(v reflect.Value) (stack.StackValue, error) {
+	if v.Type() == stackValueType {
+		return v.Interface().(stack.StackValue), nil
+	} else if v.Type() == stackFuncType {
+		return stack.NewFuncValue(v.Interface().(func(st *stack.Stack) error)), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return stack.NewFloatValue(v.Float()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return stack.NewIntValue(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return stack.NewNullValue(), fmt.Errorf("%d doesn't fit in an Int", v.Uint())
+		}
+		return stack.NewIntValue(int64(v.Uint())), nil
+	case reflect.String:
+		return stack.NewStringValue(v.String()), nil
+	case reflect.Bool:
+		return stack.NewBoolValue(v.Bool()), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return stack.NewBytesValue(slices.Clone(v.Bytes())), nil
+		}
+
+		items := make([]stack.StackValue, v.Len())
+		for i := range v.Len() {
+			if item, err := toStackValue(v.Index(i)); err != nil {
+				return stack.NewNullValue(), err
+			} else {
+				items[i] = item
+			}
+		}
+		return stack.NewListValue(items...), nil
+	case reflect.Map:
+		// Go maps have no order, so the entries are added in the order of their keys
+		entries := make([][2]stack.StackValue, 0, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			key, err := toStackValue(iter.Key())
+			if err != nil {
+				return stack.NewNullValue(), err
+			}
+
+			value, err := toStackValue(iter.Value())
+			if err != nil {
+				return stack.NewNullValue(), err
+			}
+
+			entries = append(entries, [2]stack.StackValue{key, value})
+		}
+		slices.SortFunc(entries, func(a, b [2]stack.StackValue) int {
+			return a[0].Compare(b[0])
+		})
+
+		m := stack.NewValueMap()
+		for _, e := range entries {
+			m.Set(e[0], e[1])
+		}
+		return stack.NewMapValue(m), nil
+	}
+
+	panic(fmt.Sprintf("type '%s' cannot be converted to a stack value", v.Type()))
+}
+
+/*
+Wraps an ordinary Go function so it can be called from bytecode;
+the parameters are popped off the stack with the last parameter being the top of the stack,
+the results are pushed in order, and if the last result is an error, it's used to set the error flag instead of being pushed;
+nothing is popped if an argument can't be converted, and nothing is pushed if a result can't be, like a uint64 that's too large for an Int
+
+Ints can be any Go integer type, and Go float types take either kind of number, strings are string, bools are bool, bytes are []byte, lists are slices of any other supported type,
+maps are Go maps with keys of a type that converts to a Key kind, functions are func(st *stack.Stack) error, and stack.StackValue accepts any value
+*/
+func Bind(fn any) (func(st *stack.Stack) error, error) {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func {
+		return nil, fmt.Errorf("cannot bind '%T', it's not a function", fn)
+	}
+
+	ft := fv.Type()
+	if ft.IsVariadic() {
+		return nil, fmt.Errorf("cannot bind '%T', variadic functions are not supported", fn)
+	}
+
+	kinds := make([]stack.ValueKind, ft.NumIn())
+	for i := range ft.NumIn() {
+		if kind, err := kindOfType(ft.In(i)); err != nil {
+			return nil, err
+		} else {
+			kinds[i] = kind
+		}
+	}
+
+	results := ft.NumOut()
+	returnsErr := results > 0 && ft.Out(results-1) == errorType
+	if returnsErr {
+		results--
+	}
+
+	for i := range results {
+		if _, err := kindOfType(ft.Out(i)); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(st *stack.Stack) error {
+		if err := st.Expect(kinds...); err != nil {
+			return err
+		}
+
+		// the arguments are converted before any are popped, so a failed conversion leaves the stack as it was
+		args := make([]reflect.Value, len(kinds))
+		base := len(*st) - len(kinds)
+		for i := range kinds {
+			if arg, err := fromStackValue((*st)[base+i], ft.In(i)); err != nil {
+				return err
+			} else {
+				args[i] = arg
+			}
+		}
+		for range kinds {
+			st.Pop()
+		}
+
+		out := fv.Call(args)
+
+		if returnsErr && !out[results].IsNil() {
+			return out[results].Interface().(error)
+		}
+
+		values := make([]stack.StackValue, results)
+		for i, v := range out[:results] {
+			if value, err := toStackValue(v); err != nil {
+				return err
+			} else {
+				values[i] = value
+			}
+		}
+
+		for _, value := range values {
+			st.Push(value)
+		}
+
+		return nil
+	}, nil
+}
+
+/*
+Binds an ordinary Go function with Bind and registers it with the given name
+*/
+func (vm *VelvetVM) RegisterGoFunction(name string, fn any) error {
+	bound, err := Bind(fn)
+	if err != nil {
+		return err
+	}
+	return vm.RegisterFunction(name, bound)
+}
@@ -0,0 +1,65 @@
+package vm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/voidwyrm-2/velvet-vm/velvet/vm/stack"
+)
+
+func TestBindLeavesStackOnFailedConversion(t *testing.T) {
+	fn, err := Bind(func(a string, b int8) string {
+		return a
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 300 doesn't fit in an int8, so the string before it mustn't be popped either
+	st := stack.New()
+	st.Push(stack.NewStringValue("a"))
+	st.Push(stack.NewIntValue(300))
+
+	if err := fn(&st); err == nil {
+		t.Fatal("expected an error converting 300 to an int8")
+	}
+
+	if len(st) != 2 || st[0].GetString() != "a" || st[1].GetInt() != 300 {
+		t.Errorf("the stack changed to %s", st.Dump())
+	}
+}
+
+func TestBindRejectsUintsTooLargeForInt(t *testing.T) {
+	fn, err := Bind(func() uint64 {
+		return math.MaxUint64
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := stack.New()
+	if err := fn(&st); err == nil {
+		t.Fatal("expected an error converting MaxUint64 to an Int")
+	} else if len(st) != 0 {
+		t.Errorf("expected nothing to be pushed, but the stack is %s", st.Dump())
+	}
+}
+
+func TestBindConvertsArgumentsAndResults(t *testing.T) {
+	fn, err := Bind(func(a string, b uint64) (string, uint64) {
+		return a + "!", b * 2
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := stack.New()
+	st.Push(stack.NewStringValue("a"))
+	st.Push(stack.NewIntValue(21))
+
+	if err := fn(&st); err != nil {
+		t.Fatal(err)
+	} else if len(st) != 2 || st[0].GetString() != "a!" || st[1].GetInt() != 42 {
+		t.Errorf("expected [a! 42], but the stack is %s", st.Dump())
+	}
+}
@@ -1,5 +1,5 @@
 package vm
 
 func exactIsBranch(flags uint8) (uint8, bool) {
-	return flags & 0b111, flags&0b1000 == 1
+	return flags & 0b111, flags&0b1000 != 0
 }
@@ -0,0 +1,93 @@
+package vm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBranches(t *testing.T) {
+	tests := []struct {
+		branch string
+		// the instructions that make the branch taken and not taken, the not taken one is skipped if it's empty
+		taken, notTaken string
+	}{
+		{"br", "nop", ""},
+		{"brt", "push true", "push false"},
+		{"brf", "push false", "push true"},
+		{"bre", "call error", "call reset"},
+		{"brne", "call reset", "call error"},
+	}
+
+	for _, tt := range tests {
+		for _, path := range []struct {
+			name, setup, expected string
+		}{{"taken", tt.taken, "sub\nafter\n"}, {"not taken", tt.notTaken, "after\n"}} {
+			if path.setup == "" {
+				continue
+			}
+
+			t.Run(tt.branch+" "+path.name, func(t *testing.T) {
+				// the ret before halt does nothing unless a branch that wasn't taken left a return address behind
+				source := fmt.Sprintf(`
+%s
+%s sub
+push "after"
+call println
+ret
+halt 0
+
+.sub
+  push "sub"
+  call println
+  ret
+`, path.setup, tt.branch)
+
+				out, res, err := run(t, source)
+				if err != nil {
+					t.Fatal(err)
+				} else if out != path.expected {
+					t.Errorf("expected %q, but got %q", path.expected, out)
+				} else if res.ExitCode != 0 {
+					t.Errorf("expected exit code 0, but got %d", res.ExitCode)
+				}
+			})
+		}
+	}
+}
+
+func TestNestedBranches(t *testing.T) {
+	out, _, err := run(t, `
+br outer
+push "main"
+call println
+halt 0
+
+.outer
+  push "outer start"
+  call println
+  br inner
+  push "outer end"
+  call println
+  ret
+
+.inner
+  push "inner start"
+  call println
+  br innermost
+  push "inner end"
+  call println
+  ret
+
+.innermost
+  push "innermost"
+  call println
+  ret
+`)
+
+	expected := "outer start\ninner start\ninnermost\ninner end\nouter end\nmain\n"
+	if err != nil {
+		t.Fatal(err)
+	} else if out != expected {
+		t.Errorf("expected %q, but got %q", expected, out)
+	}
+}
@@ -0,0 +1,94 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		name, source, expected string
+	}{
+		{"bytesNew", "push 3\ncall bytesNew", "<00 00 00>"},
+		{"toBytes", "push \"hi\"\ncall toBytes", "<68 69>"},
+		{"bytesToString", "push \"hi\"\ncall toBytes\ncall bytesToString", "hi"},
+		{"bytesSet", "push 2\ncall bytesNew\npush 1\npush 255\ncall bytesSet", "<00 ff>"},
+		{"bytesSlice", "push \"hello\"\ncall toBytes\npush 1\npush 3\ncall bytesSlice", "<65 6c>"},
+		{"bytesConcat", "push \"a\"\ncall toBytes\npush \"b\"\ncall toBytes\ncall bytesConcat", "<61 62>"},
+		{"index", "push \"hi\"\ncall toBytes\npush 1\ncall index", "105"},
+		{"len", "push \"hello\"\ncall toBytes\ncall len", "5"},
+		{"typeof", "push 1\ncall bytesNew\ncall typeof", "Bytes"},
+		{"equal", "push \"a\"\ncall toBytes\npush \"a\"\ncall toBytes\neq", "true"},
+		// the slice is a copy, so setting it doesn't change the original
+		{"slice copies", "@vars 1\npush 2\ncall bytesNew\nset 0\nget 0\npush 0\npush 1\ncall bytesSlice\npush 0\npush 9\ncall bytesSet\npop\nget 0", "<00 00>"},
+		// bytes are references, so setting a copy made with dup changes the original
+		{"dup shares", "push 1\ncall bytesNew\ndup\npush 0\npush 9\ncall bytesSet\npop", "<09>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, _, err := run(t, tt.source+"\ncall println\nhalt 0")
+			if err != nil {
+				t.Fatal(err)
+			} else if out != tt.expected+"\n" {
+				t.Errorf("expected %q, but got %q", tt.expected+"\n", out)
+			}
+		})
+	}
+}
+
+func TestBytesErrors(t *testing.T) {
+	tests := []struct {
+		name, source, expected string
+	}{
+		{"set out of range", "push 1\ncall bytesNew\npush 1\npush 0\ncall bytesSet", "index 1 is out of range of bytes of length 1"},
+		{"set too large", "push 1\ncall bytesNew\npush 0\npush 256\ncall bytesSet", "256 doesn't fit in a byte"},
+		{"set negative", "push 1\ncall bytesNew\npush 0\npush -1\ncall bytesSet", "-1 doesn't fit in a byte"},
+		{"slice out of range", "push 1\ncall bytesNew\npush 0\npush 2\ncall bytesSlice", "slice 0:2 is out of range of bytes of length 1"},
+		{"slice backwards", "push 2\ncall bytesNew\npush 1\npush 0\ncall bytesSlice", "slice 1:0 is out of range of bytes of length 2"},
+		{"index out of range", "push 1\ncall bytesNew\npush 1\ncall index", "index 1 is out of range of bytes of length 1"},
+		{"bytesNew negative", "push -1\ncall bytesNew", "bytes size cannot be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if out := runRejected(t, tt.source); out != tt.expected+"\n" {
+				t.Errorf("expected %q, but got %q", tt.expected+"\n", out)
+			}
+		})
+	}
+}
+
+func TestBinaryIO(t *testing.T) {
+	source := `
+call readb
+call writeb
+push 3
+call readBytes
+call writeb
+push 10
+call readBytes
+call writeb
+push 1
+call readBytes
+jne more
+pusherr
+call println
+halt 0
+
+.more
+  halt 1
+`
+
+	out, res, err := run(t, source, WithStdin(strings.NewReader("line\nabcdefg")))
+	if err != nil {
+		t.Fatal(err)
+	} else if res.ExitCode != 0 {
+		t.Error("expected reading past the end of the input to set the error flag")
+	}
+
+	// readBytes gives back fewer bytes at the end of the input, and then eof
+	if expected := "lineabcdefg" + "eof\n"; out != expected {
+		t.Errorf("expected %q, but got %q", expected, out)
+	}
+}
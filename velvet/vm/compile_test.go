@@ -0,0 +1,43 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/voidwyrm-2/velvet-vm/velvc/generation"
+	"github.com/voidwyrm-2/velvet-vm/velvc/lexer"
+	"github.com/voidwyrm-2/velvet-vm/velvc/parser"
+	"github.com/voidwyrm-2/velvet-vm/velvc/sectioner"
+)
+
+// Compiles velvc assembly, failing the test if it doesn't compile
+func compile(t testing.TB, source string) []byte {
+	t.Helper()
+
+	lex := lexer.New(strings.TrimSpace(source))
+	toks, err := lex.Lex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := parser.New(sectioner.SectionIntoLines(toks)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gen := generation.New(nodes, 0)
+	if err := gen.Generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	return gen.Bytes()
+}
+
+// Compiles and runs velvc assembly with the given options, returning what it printed
+func run(t *testing.T, source string, options ...Option) (string, Result, error) {
+	t.Helper()
+
+	var out strings.Builder
+	res, err := New(append([]Option{WithStdout(&out)}, options...)...).Run(compile(t, source), false, false)
+	return out.String(), res, err
+}
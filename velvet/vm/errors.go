@@ -0,0 +1,85 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/voidwyrm-2/velvet-vm/velvet/vm/stack"
+)
+
+var opcodeNames = []string{
+	"nop",
+	"ret",
+	"halt",
+	"call",
+	"push",
+	"pop",
+	"dup",
+	"swap",
+	"rot",
+	"set/get",
+	"jump/branch",
+}
+
+func opcodeName(opcode uint16) string {
+	if int(opcode) < len(opcodeNames) {
+		return opcodeNames[opcode]
+	} else if opcode >= firstOperator && int(opcode-firstOperator) < len(operators) {
+		return operators[opcode-firstOperator].name
+	} else if opcode >= firstFrameOpcode && int(opcode-firstFrameOpcode) < len(frameOpcodeNames) {
+		return frameOpcodeNames[opcode-firstFrameOpcode]
+	}
+	return fmt.Sprintf("opcode %d", opcode)
+}
+
+/*
+RuntimeError is returned from Run when the program can't continue executing;
+Expected and Actual are only set when Err is a *stack.ExpectError
+*/
+type RuntimeError struct {
+	PC       int
+	Opcode   uint16
+	Function string
+	Expected stack.ValueKind
+	Actual   stack.ValueKind
+	Stack    stack.Stack
+	Err      error
+}
+
+func newRuntimeError(pc int, opcode uint16, function string, st stack.Stack, err error) *RuntimeError {
+	re := &RuntimeError{PC: pc, Opcode: opcode, Function: function, Stack: slices.Clone(st), Err: err}
+
+	var ee *stack.ExpectError
+	if errors.As(err, &ee) {
+		re.Expected, re.Actual = ee.Expected, ee.Actual
+	}
+
+	return re
+}
+
+func (re *RuntimeError) Error() string {
+	if re.Function != "" {
+		return fmt.Sprintf("runtime error at %d (%s '%s'): %s", re.PC, opcodeName(re.Opcode), re.Function, re.Err.Error())
+	}
+	return fmt.Sprintf("runtime error at %d (%s): %s", re.PC, opcodeName(re.Opcode), re.Err.Error())
+}
+
+func (re *RuntimeError) Unwrap() error {
+	return re.Err
+}
+
+// returns true if the error returned from a function should stop the program instead of setting the error flag
+func isFatal(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var (
+		ee *stack.ExpectError
+		le *LimitError
+		re *RuntimeError
+	)
+	return errors.As(err, &ee) || errors.As(err, &le) || errors.As(err, &re) || errors.Is(err, ErrOutOfFuel) || errors.Is(err, ErrNegativeCost) || errors.Is(err, errHalted) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,86 @@
+package vm
+
+import (
+	"context"
+	"errors"
+
+	"github.com/voidwyrm-2/velvet-vm/velvet/vm/stack"
+)
+
+// the state of the program a VM is running, which is shared with bytecode that functions call back into
+type execution struct {
+	prog      *Program
+	fns       []func(st *stack.Stack) error
+	ctx       context.Context
+	vars      []stack.StackValue
+	callstack []frame
+	locals    []stack.StackValue
+	executed  int
+	halted    *Result
+
+	// function values are made once per run so that pushing the same function twice gives equal values
+	funcValues    []stack.StackValue
+	bytecodeFuncs map[int]stack.StackValue
+
+	dumpStack, dumpVars bool
+}
+
+// returned through functions that called back into bytecode which then halted
+var errHalted = errors.New("the program halted")
+
+// Returns the function value for the function at the given index of the program's imports
+func (ex *execution) funcValue(fn int) stack.StackValue {
+	if !ex.funcValues[fn].Is(stack.Function) {
+		ex.funcValues[fn] = stack.NewFuncValue(ex.fns[fn])
+	}
+	return ex.funcValues[fn]
+}
+
+// Returns the function value for the bytecode starting at the given instruction index
+func (vm *VelvetVM) bytecodeFunc(ex *execution, target int) stack.StackValue {
+	if fn, ok := ex.bytecodeFuncs[target]; ok {
+		return fn
+	}
+
+	fn := stack.NewBytecodeFuncValue(ex, addrOf(target), func(st *stack.Stack) error {
+		return vm.callBytecode(ex, target, st)
+	})
+	ex.bytecodeFuncs[target] = fn
+	return fn
+}
+
+/*
+Runs a bytecode function from Go until it returns, with st as its stack;
+bytecode functions can only be called while the run that created them is still going, and from the goroutine running it
+*/
+func (vm *VelvetVM) callBytecode(ex *execution, target int, st *stack.Stack) error {
+	if vm.current != ex {
+		return errors.New("a bytecode function can only be called while the program it's from is running")
+	} else if ex.halted != nil {
+		return errHalted
+	}
+
+	if st != &vm.stack {
+		outer := vm.stack
+		vm.stack = *st
+		defer func() {
+			*st, vm.stack = vm.stack, outer
+		}()
+	}
+
+	if err := vm.checkCallDepth(len(ex.callstack) + 1); err != nil {
+		return err
+	}
+
+	base, localsBase := len(ex.callstack), len(ex.locals)
+	ex.callstack = append(ex.callstack, frame{ret: -1, base: localsBase})
+
+	if err := vm.exec(ex, target, base); err != nil {
+		// the frames of the bytecode function are left behind when it doesn't return
+		ex.callstack = ex.callstack[:base]
+		clear(ex.locals[localsBase:])
+		ex.locals = ex.locals[:localsBase]
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/voidwyrm-2/velvet-vm/velvet/vm/stack"
+)
+
+// the largest width or precision a format verb can have, which is the largest that Go's fmt allows
+const maxFormatWidth = 1000000
+
+// what the format verbs that only take some kinds need
+var formatVerbKinds = map[byte]string{'d': "an Int", 'f': "a Number", 's': "a String", 'x': "an Int, String or Bytes"}
+
+// Reads the digits at the given index of a format, returning the number they make (or -1 if there are none) and the index after them
+func formatNumber(format string, i int) (int, int) {
+	n := -1
+	for ; i < len(format) && format[i] >= '0' && format[i] <= '9'; i++ {
+		n = max(n, 0)*10 + int(format[i]-'0')
+		if n > maxFormatWidth {
+			n = maxFormatWidth + 1
+		}
+	}
+	return n, i
+}
+
+/*
+Formats values with a printf style format string;
+the verbs are %d for Ints, %f for numbers, %s for strings, %v for any value the way print prints it,
+%x for Ints, strings and bytes in hex, and %% for a percent sign.
+Verbs can have the flags '-' to pad on the right and '0' to pad with zeros, a width, and a precision for %f and %s
+*/
+func sprintf(format string, args []stack.StackValue) (string, error) {
+	var out strings.Builder
+	next := 0
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+
+		// the spec is everything from the percent sign up to and including the verb
+		start := i
+		for i++; i < len(format) && strings.IndexByte("-0", format[i]) >= 0; i++ {
+		}
+		var width int
+		width, i = formatNumber(format, i)
+
+		precision := -1
+		if i < len(format) && format[i] == '.' {
+			precision, i = formatNumber(format, i+1)
+		}
+		if i >= len(format) {
+			return "", fmt.Errorf("format verb '%s' is never finished", format[start:])
+		}
+
+		spec, verb := format[start:i+1], format[i]
+		if width > maxFormatWidth || precision > maxFormatWidth {
+			return "", fmt.Errorf("format verb '%s' has a width or precision larger than %d", spec, maxFormatWidth)
+		}
+
+		if strings.IndexByte("dfsvx%", verb) < 0 {
+			return "", fmt.Errorf("unknown format verb '%s'", spec)
+		} else if verb == '%' {
+			if len(spec) > 2 {
+				return "", fmt.Errorf("format verb '%s' cannot have flags, a width or a precision", spec)
+			}
+			out.WriteByte('%')
+			continue
+		}
+
+		if next >= len(args) {
+			return "", fmt.Errorf("format verb '%s' has no argument", spec)
+		}
+		arg := args[next]
+		next++
+
+		// the verbs other than %v only take some kinds
+		var value any
+		switch {
+		case verb == 'd' && arg.Is(stack.Int):
+			value = arg.GetInt()
+		case verb == 'f' && arg.Is(stack.Number):
+			value = arg.GetFloat()
+		case verb == 's' && arg.Is(stack.String):
+			value = arg.GetString()
+		case verb == 'v':
+			value, spec = arg.Format(), spec[:len(spec)-1]+"s"
+		case verb == 'x' && arg.Is(stack.Int):
+			value = arg.GetInt()
+		case verb == 'x' && arg.Is(stack.String):
+			value = arg.GetString()
+		case verb == 'x' && arg.Is(stack.Bytes):
+			value = arg.GetBytes()
+		default:
+			return "", fmt.Errorf("format verb '%s' needs %s, but argument %d is a %s", spec, formatVerbKinds[verb], next, arg.GetKind().Name())
+		}
+
+		// the spec has been checked, so Go's fmt can do the padding and precision
+		fmt.Fprintf(&out, spec, value)
+	}
+
+	if next < len(args) {
+		return "", fmt.Errorf("there are %d arguments, but the format only uses %d", len(args), next)
+	}
+
+	return out.String(), nil
+}
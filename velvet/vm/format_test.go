@@ -0,0 +1,79 @@
+package vm
+
+import "testing"
+
+func TestPrintf(t *testing.T) {
+	tests := []struct {
+		format, args, expected string
+	}{
+		{"%d and %s", "[42 \"hi\"]", "42 and hi"},
+		{"%5d|", "[42]", "   42|"},
+		{"%-5d|", "[42]", "42   |"},
+		{"%05d", "[42]", "00042"},
+		{"%05d", "[-42]", "-0042"},
+		{"%.2f", "[3.14159]", "3.14"},
+		{"%8.3f|", "[2]", "   2.000|"},
+		{"%.2s", "[\"hello\"]", "he"},
+		{"%-4s|", "[\"ab\"]", "ab  |"},
+		{"%v %v", "[[1 \"a\"] null]", "[ 1 \"a\" ] null"},
+		{"%4v|", "[true]", "true|"},
+		{"%x %x", "[255 \"hi\"]", "ff 6869"},
+		{"100%%", "[]", "100%"},
+		{"%d%%", "[5]", "5%"},
+		{"no verbs", "[]", "no verbs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			out, _, err := run(t, "push \""+tt.format+"\"\npush "+tt.args+"\ncall printf\nhalt 0")
+			if err != nil {
+				t.Fatal(err)
+			} else if out != tt.expected {
+				t.Errorf("expected %q, but got %q", tt.expected, out)
+			}
+		})
+	}
+}
+
+func TestSprintf(t *testing.T) {
+	out, _, err := run(t, "push \"%03d\"\npush [7]\ncall sprintf\ncall typeof\ncall println\nhalt 0")
+	if err != nil {
+		t.Fatal(err)
+	} else if out != "String\n" {
+		t.Errorf("expected sprintf to return a String, but got %q", out)
+	}
+
+	out, _, err = run(t, "push \"%03d\"\npush [7]\ncall sprintf\ncall println\nhalt 0")
+	if err != nil {
+		t.Fatal(err)
+	} else if out != "007\n" {
+		t.Errorf("expected %q, but got %q", "007\n", out)
+	}
+}
+
+func TestPrintfErrors(t *testing.T) {
+	tests := []struct {
+		name, format, args, expected string
+	}{
+		{"too few arguments", "%d %d", "[1]", "format verb '%d' has no argument"},
+		{"too many arguments", "%d", "[1 2]", "there are 2 arguments, but the format only uses 1"},
+		{"int verb", "%d", "[1.5]", "format verb '%d' needs an Int, but argument 1 is a Float"},
+		{"number verb", "%f", "[\"a\"]", "format verb '%f' needs a Number, but argument 1 is a String"},
+		{"string verb", "%s", "[1]", "format verb '%s' needs a String, but argument 1 is a Int"},
+		{"hex verb", "%x", "[true]", "format verb '%x' needs an Int, String or Bytes, but argument 1 is a Bool"},
+		{"unknown verb", "%q", "[1]", "unknown format verb '%q'"},
+		{"unfinished verb", "abc %5", "[1]", "format verb '%5' is never finished"},
+		{"percent with a width", "%5%", "[]", "format verb '%5%' cannot have flags, a width or a precision"},
+		{"width too large", "%9999999d", "[1]", "format verb '%9999999d' has a width or precision larger than 1000000"},
+	}
+
+	for _, tt := range tests {
+		for _, function := range []string{"printf", "sprintf"} {
+			t.Run(function+" "+tt.name, func(t *testing.T) {
+				if out := runRejected(t, "push \""+tt.format+"\"\npush "+tt.args+"\ncall "+function); out != tt.expected+"\n" {
+					t.Errorf("expected %q, but got %q", tt.expected+"\n", out)
+				}
+			})
+		}
+	}
+}
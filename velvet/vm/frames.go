@@ -0,0 +1,72 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/voidwyrm-2/velvet-vm/velvet/vm/stack"
+)
+
+// the opcode of the first call frame instruction, the rest follow in the order of frameOpcodeNames
+const firstFrameOpcode uint16 = 28
+
+var frameOpcodeNames = []string{
+	"enter",
+	"lset/lget",
+}
+
+/*
+A frame is pushed onto the call stack by each branch that's taken and popped by ret;
+its local slots are created by enter and are the end of the VM's locals, starting at base
+*/
+type frame struct {
+	ret     int // the index of the instruction that ret continues from
+	base    int
+	entered bool
+}
+
+var (
+	errEnterOutsideFrame = errors.New("enter can only be used in a subroutine that was branched to")
+	errEnterTwice        = errors.New("enter can only be used once per subroutine call")
+)
+
+/*
+Creates the local slots of the current frame, moving the given number of arguments off the stack into the first slots;
+the last argument is the top of the stack, and the slots after the arguments start as Null
+*/
+func enterFrame(callstack []frame, locals []stack.StackValue, st *stack.Stack, args, size int) ([]stack.StackValue, error) {
+	if len(callstack) == 0 {
+		return locals, errEnterOutsideFrame
+	}
+
+	f := &callstack[len(callstack)-1]
+	if f.entered {
+		return locals, errEnterTwice
+	} else if len(*st) < args {
+		return locals, &stack.ExpectError{Expected: stack.Any, Underflow: true}
+	}
+
+	f.entered = true
+	locals = append(locals, (*st)[len(*st)-args:]...)
+	for range size {
+		locals = append(locals, stack.NewNullValue())
+	}
+	*st = (*st)[:len(*st)-args]
+
+	return locals, nil
+}
+
+// Returns the local slots of the current frame, the slot index must be checked with localSlot
+func frameLocals(callstack []frame, locals []stack.StackValue) []stack.StackValue {
+	if len(callstack) == 0 {
+		return nil
+	}
+	return locals[callstack[len(callstack)-1].base:]
+}
+
+func localSlot(frameLocals []stack.StackValue, index uint16) error {
+	if int(index) >= len(frameLocals) {
+		return fmt.Errorf("%d is not a valid local slot, the current frame has %d", index, len(frameLocals))
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package vm
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestRecursiveFrames(t *testing.T) {
+	source, err := os.ReadFile("../../examples/fibrec.velv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out, _, err := run(t, string(source)); err != nil {
+		t.Fatal(err)
+	} else if out != "6765\n" {
+		t.Errorf("expected %q, but got %q", "6765\n", out)
+	}
+}
+
+func TestFrameArguments(t *testing.T) {
+	// the arguments keep their order, and the slots after them start as null
+	out, res, err := run(t, `
+push 1
+push 2
+br f
+halt 0
+
+.f
+  enter 2 1
+  lget 0
+  call println
+  lget 1
+  call println
+  lget 2
+  call println
+  ret
+`)
+
+	if err != nil {
+		t.Fatal(err)
+	} else if out != "1\n2\nnull\n" {
+		t.Errorf("expected %q, but got %q", "1\n2\nnull\n", out)
+	} else if len(res.Stack) != 0 {
+		t.Errorf("expected the arguments to be moved off the stack, but the stack is %s", res.Stack.Dump())
+	}
+}
+
+func TestFrameErrors(t *testing.T) {
+	tests := []struct {
+		name, source string
+		expected     error
+	}{
+		{"enter outside a frame", "enter 0 1\nhalt 0", errEnterOutsideFrame},
+		{"enter twice", "br f\nhalt 0\n.f\n  enter 0 1\n  enter 0 1\n  ret", errEnterTwice},
+		{"missing arguments", "br f\nhalt 0\n.f\n  enter 2 0\n  ret", nil},
+		{"slot out of range", "br f\nhalt 0\n.f\n  enter 0 1\n  lget 1\n  ret", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := run(t, tt.source)
+
+			var re *RuntimeError
+			if !errors.As(err, &re) {
+				t.Fatalf("expected a *RuntimeError, but got %v", err)
+			} else if tt.expected != nil && !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, but got %v", tt.expected, err)
+			}
+		})
+	}
+}
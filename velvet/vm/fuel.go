@@ -0,0 +1,40 @@
+package vm
+
+import "errors"
+
+var (
+	// ErrOutOfFuel is the error a RuntimeError wraps when a run uses up its fuel budget
+	ErrOutOfFuel = errors.New("out of fuel")
+	// ErrNegativeCost is returned by Charge when it's given a negative cost, which would add fuel instead of using it
+	ErrNegativeCost = errors.New("fuel cost cannot be negative")
+)
+
+/*
+Uses up the given amount of fuel, returning ErrOutOfFuel if there isn't enough left, or ErrNegativeCost if the cost is negative;
+functions should return the error so that the program is stopped
+*/
+func (vm *VelvetVM) Charge(cost int64) error {
+	if cost < 0 {
+		return ErrNegativeCost
+	} else if vm.fuelBudget <= 0 {
+		return nil
+	}
+
+	if vm.fuel < cost {
+		vm.fuel = 0
+		return ErrOutOfFuel
+	}
+
+	vm.fuel -= cost
+	return nil
+}
+
+/*
+Returns how much fuel is left from the current or last run, or -1 if there's no fuel budget
+*/
+func (vm *VelvetVM) Fuel() int64 {
+	if vm.fuelBudget <= 0 {
+		return -1
+	}
+	return vm.fuel
+}
@@ -0,0 +1,34 @@
+package vm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestChargeRejectsNegativeCost(t *testing.T) {
+	for _, budget := range []int64{0, 100} {
+		vm := New(WithFuel(budget))
+		vm.fuel = budget
+
+		if err := vm.Charge(-50); !errors.Is(err, ErrNegativeCost) {
+			t.Errorf("with a budget of %d, expected ErrNegativeCost, but got %v", budget, err)
+		} else if vm.fuel != budget {
+			t.Errorf("with a budget of %d, the fuel changed to %d", budget, vm.fuel)
+		}
+	}
+}
+
+func TestCharge(t *testing.T) {
+	vm := New(WithFuel(100))
+	vm.fuel = 100
+
+	if err := vm.Charge(60); err != nil {
+		t.Fatal(err)
+	} else if vm.Fuel() != 40 {
+		t.Errorf("expected 40 fuel left, but there's %d", vm.Fuel())
+	}
+
+	if err := vm.Charge(60); !errors.Is(err, ErrOutOfFuel) {
+		t.Errorf("expected ErrOutOfFuel, but got %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/voidwyrm-2/velvet-vm/velvet/vm/stack"
+)
+
+// checks that a function name can be used with the call instruction
+func validateFunctionName(name string) error {
+	if name == "" {
+		return errors.New("function names cannot be empty")
+	} else if name == "getErr" {
+		return fmt.Errorf("'%s' is reserved by the VM", name)
+	} else if strings.ContainsAny(name, " \t\r\n\"") {
+		return fmt.Errorf("'%s' is not a valid function name", name)
+	} else if strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") || strings.Contains(name, "..") {
+		return fmt.Errorf("'%s' is not a valid function name", name)
+	}
+	return nil
+}
+
+/*
+Returns the function with the given name, looking at the functions registered on the VM before the standard library
+*/
+func (vm *VelvetVM) lookup(name string) (func(st *stack.Stack) error, bool) {
+	if fn, ok := vm.callables[name]; ok {
+		// a nil entry marks an unregistered standard library function
+		return fn, fn != nil
+	}
+
+	if fn, ok := stdfn[name]; ok {
+		return func(st *stack.Stack) error {
+			return fn(vm, st)
+		}, true
+	}
+
+	return nil, false
+}
+
+/*
+Registers a function that can be called from bytecode with the given name;
+registering a function with the same name as an existing one replaces it for this VM only
+*/
+func (vm *VelvetVM) RegisterFunction(name string, fn func(st *stack.Stack) error) error {
+	if err := validateFunctionName(name); err != nil {
+		return err
+	} else if fn == nil {
+		return fmt.Errorf("cannot register a nil function as '%s'", name)
+	}
+
+	vm.callables[name] = fn
+	return nil
+}
+
+/*
+Registers each of the given functions as "namespace.name", e.g. "net" and "dial" are registered as "net.dial";
+every name is checked first, so if any of them is invalid or is already a function on the VM, none of them are registered
+*/
+func (vm *VelvetVM) RegisterNamespace(namespace string, fns map[string]func(st *stack.Stack) error) error {
+	if err := validateFunctionName(namespace); err != nil {
+		return err
+	}
+
+	// the names are checked in order so that the same error is returned every time
+	names := make([]string, 0, len(fns))
+	for name := range fns {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		full := namespace + "." + name
+		if err := validateFunctionName(full); err != nil {
+			return err
+		} else if fns[name] == nil {
+			return fmt.Errorf("cannot register a nil function as '%s'", full)
+		} else if _, ok := vm.lookup(full); ok {
+			return fmt.Errorf("'%s' is already a function", full)
+		}
+	}
+
+	for _, name := range names {
+		vm.callables[namespace+"."+name] = fns[name]
+	}
+
+	return nil
+}
+
+/*
+Removes a function from this VM, including standard library functions;
+returns false if there was no function with the given name
+*/
+func (vm *VelvetVM) Unregister(name string) bool {
+	if _, ok := vm.lookup(name); !ok {
+		return false
+	}
+
+	if _, ok := stdfn[name]; ok {
+		vm.callables[name] = nil
+	} else {
+		delete(vm.callables, name)
+	}
+
+	return true
+}
+
+/*
+Removes every function in the given namespace from this VM, returning how many were removed
+*/
+func (vm *VelvetVM) UnregisterNamespace(namespace string) int {
+	removed := 0
+	prefix := namespace + "."
+
+	for name := range vm.callables {
+		if strings.HasPrefix(name, prefix) && vm.Unregister(name) {
+			removed++
+		}
+	}
+
+	for name := range stdfn {
+		if strings.HasPrefix(name, prefix) && vm.Unregister(name) {
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// LinkError is returned when a program uses functions that don't exist on the VM
+type LinkError struct {
+	Missing []string
+}
+
+func (le *LinkError) Error() string {
+	return fmt.Sprintf("missing imports: %s", strings.Join(le.Missing, ", "))
+}
+
+/*
+Resolves the import table of a program to this VM's functions, returning a *LinkError listing every function that doesn't exist
+*/
+func (vm *VelvetVM) link(prog *Program) ([]func(st *stack.Stack) error, error) {
+	fns := make([]func(st *stack.Stack) error, len(prog.imports))
+	missing := []string{}
+
+	for i, name := range prog.imports {
+		if fn, ok := vm.lookup(name); ok {
+			fns[i] = fn
+		} else {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, &LinkError{Missing: missing}
+	}
+	return fns, nil
+}
+
+/*
+Checks that every function the program uses exists on this VM, returning a *LinkError listing the ones that don't
+*/
+func (vm *VelvetVM) Link(prog *Program) error {
+	_, err := vm.link(prog)
+	return err
+}
@@ -0,0 +1,47 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/voidwyrm-2/velvet-vm/velvet/vm/stack"
+)
+
+func nop(st *stack.Stack) error {
+	return nil
+}
+
+func TestRegisterNamespaceRegistersNothingOnError(t *testing.T) {
+	for _, fns := range []map[string]func(st *stack.Stack) error{
+		{"a": nop, "b": nop, "bad name": nop, "c": nop},
+		{"a": nop, "b": nop, "c": nil},
+		{"a": nop, "taken": nop, "c": nop},
+	} {
+		vm := New()
+		if err := vm.RegisterFunction("net.taken", nop); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := vm.RegisterNamespace("net", fns); err == nil {
+			t.Fatalf("registering %v should fail", fns)
+		}
+
+		for _, name := range []string{"net.a", "net.b", "net.c"} {
+			if _, ok := vm.lookup(name); ok {
+				t.Errorf("'%s' was registered even though the namespace failed", name)
+			}
+		}
+	}
+}
+
+func TestRegisterNamespace(t *testing.T) {
+	vm := New()
+	if err := vm.RegisterNamespace("net", map[string]func(st *stack.Stack) error{"dial": nop, "listen": nop}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"net.dial", "net.listen"} {
+		if _, ok := vm.lookup(name); !ok {
+			t.Errorf("'%s' was not registered", name)
+		}
+	}
+}
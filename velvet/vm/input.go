@@ -0,0 +1,104 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+type readResult struct {
+	data []byte
+	err  error
+}
+
+/*
+Wraps the VM's input so that blocking reads stop when the context of the current run is done;
+at most one read of the underlying reader is in flight at a time, and its data is kept for the next read if the run stops first
+
+When the run stops, the in-flight read is interrupted if the reader has read deadlines (like an *os.File made with os.Pipe),
+and the reader can still be read by later runs.
+The VM doesn't own its reader, so other readers (like a terminal, or an *io.PipeReader) are never closed;
+the goroutine doing the read stays blocked until the reader returns, and its data is then given to the next read
+*/
+type ctxReader struct {
+	r           io.Reader
+	ctx         context.Context
+	pending     chan readResult
+	interrupted bool
+	buf         []byte
+	err         error
+}
+
+func newCtxReader(r io.Reader) *ctxReader {
+	return &ctxReader{r: r, ctx: context.Background()}
+}
+
+// readers that can have a blocking read interrupted, like os.File when it's a pipe
+type deadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+func (cr *ctxReader) Read(p []byte) (int, error) {
+	if len(cr.buf) > 0 {
+		n := copy(p, cr.buf)
+		cr.buf = cr.buf[n:]
+		return n, nil
+	} else if cr.err != nil {
+		err := cr.err
+		cr.err = nil
+		return 0, err
+	}
+
+	if cr.pending == nil {
+		// nothing can interrupt the read, so there's no need for a goroutine
+		if cr.ctx.Done() == nil {
+			return cr.r.Read(p)
+		}
+
+		pending := make(chan readResult, 1)
+		cr.pending = pending
+
+		go func(size int) {
+			b := make([]byte, size)
+			n, err := cr.r.Read(b)
+			pending <- readResult{data: b[:n], err: err}
+		}(len(p))
+	}
+
+	select {
+	case res := <-cr.pending:
+		cr.pending = nil
+
+		if cr.interrupted {
+			cr.interrupted = false
+			if d, ok := cr.r.(deadliner); ok {
+				d.SetReadDeadline(time.Time{})
+			}
+
+			if errors.Is(res.err, os.ErrDeadlineExceeded) {
+				res.err = nil
+			}
+		}
+
+		n := copy(p, res.data)
+		cr.buf = res.data[n:]
+
+		if len(cr.buf) > 0 {
+			cr.err = res.err
+			return n, nil
+		}
+		return n, res.err
+	case <-cr.ctx.Done():
+		cr.unblock()
+		return 0, cr.ctx.Err()
+	}
+}
+
+// Makes the in-flight read return early by setting a read deadline, if the reader has them
+func (cr *ctxReader) unblock() {
+	if d, ok := cr.r.(deadliner); ok && d.SetReadDeadline(time.Now()) == nil {
+		cr.interrupted = true
+	}
+}
@@ -0,0 +1,104 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Waits for the amount of goroutines to drop to at most the given amount
+func waitForGoroutines(t *testing.T, max int) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > max; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines are still running, expected at most %d", runtime.NumGoroutine(), max)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Runs a program that reads a line with a context that times out, and checks that it timed out
+func runTimedOutRead(t *testing.T, virmac *VelvetVM) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := virmac.RunContext(ctx, compile(t, "call readt\nhalt 0"), false, false)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the run to time out, but got %v", err)
+	}
+}
+
+func TestCancelledReadInterruptsFile(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	defer pw.Close()
+
+	before := runtime.NumGoroutine()
+
+	var out strings.Builder
+	virmac := New(WithStdin(pr), WithStdout(&out))
+	runTimedOutRead(t, virmac)
+
+	// the read is interrupted with a deadline, so its goroutine has to finish without the file being closed
+	waitForGoroutines(t, before)
+
+	if _, err := pw.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	} else if _, err := virmac.Run(compile(t, "call readt\ncall println\nhalt 0"), false, false); err != nil {
+		t.Fatal(err)
+	} else if out.String() != "hello\n" {
+		t.Errorf("expected the next run to read %q, but got %q", "hello\n", out.String())
+	}
+}
+
+func TestCancelledReadLeavesPipeOpen(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pr.Close()
+
+	var out strings.Builder
+	virmac := New(WithStdin(pr), WithStdout(&out))
+	runTimedOutRead(t, virmac)
+
+	// an io.Pipe can't be interrupted, so the pending read gets what's written and gives it to the next run
+	go pw.Write([]byte("hello\n"))
+
+	if _, err := virmac.Run(compile(t, "call readt\ncall println\nhalt 0"), false, false); err != nil {
+		t.Fatal(err)
+	} else if out.String() != "hello\n" {
+		t.Errorf("expected the next run to read %q, but got %q", "hello\n", out.String())
+	}
+}
+
+func TestUnblockNeverClosesFiles(t *testing.T) {
+	// regular files don't have read deadlines, like stdin when it's a terminal
+	f, err := os.CreateTemp(t.TempDir(), "input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString("hello"); err != nil {
+		t.Fatal(err)
+	} else if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	newCtxReader(f).unblock()
+
+	if b, err := io.ReadAll(f); err != nil {
+		t.Errorf("expected the file to still be open, but got %v", err)
+	} else if string(b) != "hello" {
+		t.Errorf("expected to read %q, but got %q", "hello", b)
+	}
+}
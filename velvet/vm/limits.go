@@ -0,0 +1,71 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxSize is the largest a list, map, string or Bytes value can be, even when the VM has no limit on it
+const MaxSize = math.MaxInt32
+
+/*
+Limits caps the resources a single run can use, a limit of zero or less means there's no limit;
+without a limit, lists, maps, strings and bytes can still only be as large as MaxSize
+*/
+type Limits struct {
+	StackDepth  int // how many values the stack and the local slots of every frame can hold together
+	CallDepth   int // how many return addresses can be on the return address stack
+	ListLength  int // how many items a list or entries a map can have
+	StringBytes int // how many bytes a string or a Bytes value can have
+}
+
+// Sets the resource limits of the VM, by default there are no limits
+func WithLimits(limits Limits) Option {
+	return func(vm *VelvetVM) {
+		vm.limits = limits
+	}
+}
+
+// LimitError is the error a RuntimeError wraps when a program goes over one of its limits
+type LimitError struct {
+	Limit     string
+	Max, Size int
+}
+
+func (le *LimitError) Error() string {
+	return fmt.Sprintf("%s limit of %d exceeded (%d)", le.Limit, le.Max, le.Size)
+}
+
+func checkLimit(name string, max, size int) error {
+	if max > 0 && size > max {
+		return &LimitError{Limit: name, Max: max, Size: size}
+	}
+	return nil
+}
+
+// returns the limit, or MaxSize if there's no limit or it's larger than MaxSize
+func sizeLimit(limit int) int {
+	if limit <= 0 {
+		return MaxSize
+	}
+	return min(limit, MaxSize)
+}
+
+// Returns a *LimitError if a list of the given length would be over the VM's limit or MaxSize
+func (vm *VelvetVM) CheckListLength(length int) error {
+	return checkLimit("list length", sizeLimit(vm.limits.ListLength), length)
+}
+
+// Returns a *LimitError if a string of the given length would be over the VM's limit or MaxSize
+func (vm *VelvetVM) CheckStringBytes(length int) error {
+	return checkLimit("string size", sizeLimit(vm.limits.StringBytes), length)
+}
+
+// the local slots of frames are counted with the stack, since enter can create thousands of them at once
+func (vm *VelvetVM) checkStackDepth(locals int) error {
+	return checkLimit("stack depth", vm.limits.StackDepth, len(vm.stack)+locals)
+}
+
+func (vm *VelvetVM) checkCallDepth(depth int) error {
+	return checkLimit("call depth", vm.limits.CallDepth, depth)
+}
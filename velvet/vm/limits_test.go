@@ -0,0 +1,88 @@
+package vm
+
+import (
+	"errors"
+	"testing"
+)
+
+// Checks that a run was stopped by going over the limit with the given name
+func expectLimit(t *testing.T, err error, limit string) {
+	t.Helper()
+
+	var (
+		re *RuntimeError
+		le *LimitError
+	)
+	if !errors.As(err, &re) || !errors.As(err, &le) {
+		t.Fatalf("expected a *RuntimeError wrapping a *LimitError, but got %v", err)
+	} else if le.Limit != limit {
+		t.Errorf("expected the %s limit to be exceeded, but it was the %s limit", limit, le.Limit)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		limit, source string
+		limits        Limits
+	}{
+		{"stack depth", ".loop\n  push 1\n  j loop", Limits{StackDepth: 10}},
+		{"stack depth", ".loop\n  enter 0 65535\n  br loop", Limits{StackDepth: 100, CallDepth: 2000}},
+		{"call depth", ".loop\n  br loop", Limits{CallDepth: 10}},
+		{"list length", "push 5\ncall allocList\nhalt 0", Limits{ListLength: 4}},
+		{"list length", "push [1 2 3 4]\npush 5\ncall append\nhalt 0", Limits{ListLength: 4}},
+		{"string size", "push 5\ncall bytesNew\nhalt 0", Limits{StringBytes: 4}},
+		{"string size", "push \"abc\"\npush \"de\"\ncall concat\nhalt 0", Limits{StringBytes: 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.limit, func(t *testing.T) {
+			_, _, err := run(t, tt.source, WithLimits(tt.limits))
+			expectLimit(t, err, tt.limit)
+		})
+	}
+}
+
+func TestHugeSizesWithoutLimits(t *testing.T) {
+	tests := []struct {
+		function, source, limit string
+	}{
+		{"allocList", "call allocList", "list length"},
+		{"allocInitList", "push 0\ncall allocInitList", "list length"},
+		{"bytesNew", "call bytesNew", "string size"},
+		{"readBytes", "call readBytes", "string size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.function, func(t *testing.T) {
+			_, _, err := run(t, "push 4611686018427387904\n"+tt.source+"\nhalt 0")
+			expectLimit(t, err, tt.limit)
+		})
+	}
+}
+
+func TestNegativeSizes(t *testing.T) {
+	for _, function := range []string{"allocList", "bytesNew", "readBytes"} {
+		t.Run(function, func(t *testing.T) {
+			if out := runRejected(t, "push -1\ncall "+function); out == "" {
+				t.Error("expected an error message")
+			}
+		})
+	}
+}
+
+func TestReturnFreesLocals(t *testing.T) {
+	// each call makes 60 slots, which only fit under the limit if ret gets rid of them
+	source := `
+br f
+br f
+br f
+halt 0
+
+.f
+  enter 0 60
+  ret
+`
+	if _, _, err := run(t, source, WithLimits(Limits{StackDepth: 100})); err != nil {
+		t.Fatal(err)
+	}
+}
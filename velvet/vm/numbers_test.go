@@ -0,0 +1,54 @@
+package vm
+
+import "testing"
+
+func TestIntsAndFloats(t *testing.T) {
+	tests := []struct {
+		name, source, expected string
+	}{
+		{"big int literal", "push 9007199254740993", "9007199254740993"},
+		{"32-bit literal", "push 16777217", "16777217"},
+		{"float literal", "push 0.1", "0.1"},
+		{"negative float literal", "push -2.5", "-2.5"},
+		{"int kind", "push 1\ncall typeof", "Int"},
+		{"float kind", "push 1.0\ncall typeof", "Float"},
+		{"ints stay ints", "push 2\npush 3\nmul\ncall typeof", "Int"},
+		{"a float makes a float", "push 1\npush 1.0\nadd\ncall typeof", "Float"},
+		{"mixed arithmetic", "push 1\npush 1.5\nadd", "2.5"},
+		{"int division truncates", "push 7\npush -2\ndiv", "-3"},
+		{"float division", "push 7.0\npush 2\ndiv", "3.5"},
+		{"overflow wraps", "push 9223372036854775807\npush 1\nadd", "-9223372036854775808"},
+		{"negative power", "push 2\npush -1\npow", "0.5"},
+		{"int equals float", "push 1\npush 1.0\neq", "true"},
+		{"mixed comparison", "push 1\npush 1.5\nlt", "true"},
+		{"parse int", "push \"42\"\ncall parseNumber\ncall typeof", "Int"},
+		{"parse float", "push \"4.2\"\ncall parseNumber\ncall typeof", "Float"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, _, err := run(t, tt.source+"\ncall println\nhalt 0")
+			if err != nil {
+				t.Fatal(err)
+			} else if out != tt.expected+"\n" {
+				t.Errorf("expected %q, but got %q", tt.expected+"\n", out)
+			}
+		})
+	}
+}
+
+func TestParseNumberRejects(t *testing.T) {
+	for _, s := range []string{"", "abc", "1.2.3"} {
+		t.Run(s, func(t *testing.T) {
+			if out := runRejected(t, "push \""+s+"\"\ncall parseNumber"); out == "\n" {
+				t.Error("expected an error message")
+			}
+		})
+	}
+}
+
+func TestBitwiseOnlyTakesInts(t *testing.T) {
+	if _, _, err := run(t, "push 1.0\npush 1\nand\nhalt 0"); err == nil {
+		t.Error("expected a Float to be rejected")
+	}
+}
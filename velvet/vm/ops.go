@@ -0,0 +1,192 @@
+package vm
+
+import (
+	"errors"
+	"math"
+
+	"github.com/voidwyrm-2/velvet-vm/velvet/vm/stack"
+)
+
+// the opcode of the first operator instruction, the rest follow in the order of operators
+const firstOperator uint16 = 11
+
+var errDivisionByZero = errors.New("integer division by zero")
+
+/*
+Pops two numbers and pushes the result of an arithmetic operator;
+if both are Ints the result is an Int, otherwise both are converted to Floats and the result is a Float
+*/
+func arith(st *stack.Stack, ints func(x, y int64) (int64, error), floats func(x, y float64) float64) error {
+	if err := st.Expect(stack.Number, stack.Number); err != nil {
+		return err
+	}
+
+	y, x := st.Pop(), st.Pop()
+	if x.GetKind() == stack.Int && y.GetKind() == stack.Int {
+		if n, err := ints(x.GetInt(), y.GetInt()); err != nil {
+			return err
+		} else {
+			st.Push(stack.NewIntValue(n))
+		}
+	} else {
+		st.Push(stack.NewFloatValue(floats(x.GetFloat(), y.GetFloat())))
+	}
+
+	return nil
+}
+
+/*
+Pops two values and pushes the result of comparing them;
+two Ints are compared exactly, other numbers are compared as Floats, and anything else is compared with StackValue.Compare
+*/
+func compare(st *stack.Stack, ints func(x, y int64) bool, floats func(x, y float64) bool, order func(c int) bool) error {
+	if err := st.Expect(stack.Any, stack.Any); err != nil {
+		return err
+	}
+
+	y, x := st.Pop(), st.Pop()
+	if x.GetKind() == stack.Int && y.GetKind() == stack.Int {
+		st.Push(stack.NewBoolValue(ints(x.GetInt(), y.GetInt())))
+	} else if x.Is(stack.Number) && y.Is(stack.Number) {
+		st.Push(stack.NewBoolValue(floats(x.GetFloat(), y.GetFloat())))
+	} else {
+		st.Push(stack.NewBoolValue(order(x.Compare(y))))
+	}
+
+	return nil
+}
+
+// Pops two Ints and pushes the result of a bitwise operator
+func bitwise(st *stack.Stack, op func(x, y int64) int64) error {
+	if err := st.Expect(stack.Int, stack.Int); err != nil {
+		return err
+	}
+
+	y, x := st.Pop(), st.Pop()
+	st.Push(stack.NewIntValue(op(x.GetInt(), y.GetInt())))
+	return nil
+}
+
+// raises x to the power of y by squaring, y must not be negative
+func intPow(x, y int64) int64 {
+	n := int64(1)
+	for y > 0 {
+		if y&1 == 1 {
+			n *= x
+		}
+		x *= x
+		y >>= 1
+	}
+	return n
+}
+
+/*
+The operator instructions, which can also be called as functions with the same name for compatibility
+*/
+var operators = []struct {
+	name string
+	fn   func(st *stack.Stack) error
+}{
+	{"add", func(st *stack.Stack) error {
+		return arith(st, func(x, y int64) (int64, error) { return x + y, nil }, func(x, y float64) float64 { return x + y })
+	}},
+	{"sub", func(st *stack.Stack) error {
+		return arith(st, func(x, y int64) (int64, error) { return x - y, nil }, func(x, y float64) float64 { return x - y })
+	}},
+	{"mul", func(st *stack.Stack) error {
+		return arith(st, func(x, y int64) (int64, error) { return x * y, nil }, func(x, y float64) float64 { return x * y })
+	}},
+	{"div", func(st *stack.Stack) error {
+		return arith(st, func(x, y int64) (int64, error) {
+			if y == 0 {
+				return 0, errDivisionByZero
+			}
+			return x / y, nil
+		}, func(x, y float64) float64 { return x / y })
+	}},
+	{"pow", func(st *stack.Stack) error {
+		if err := st.Expect(stack.Number, stack.Number); err != nil {
+			return err
+		}
+
+		// a negative power of an Int isn't an Int, so it's a Float
+		if y, x := st.Pop(), st.Pop(); x.GetKind() == stack.Int && y.GetKind() == stack.Int && y.GetInt() >= 0 {
+			st.Push(stack.NewIntValue(intPow(x.GetInt(), y.GetInt())))
+		} else {
+			st.Push(stack.NewFloatValue(math.Pow(x.GetFloat(), y.GetFloat())))
+		}
+		return nil
+	}},
+	{"log", func(st *stack.Stack) error {
+		if err := st.Expect(stack.Number); err != nil {
+			return err
+		}
+		st.Push(stack.NewFloatValue(math.Log(st.Pop().GetFloat())))
+		return nil
+	}},
+	{"neg", func(st *stack.Stack) error {
+		if err := st.Expect(stack.Number); err != nil {
+			return err
+		}
+
+		if x := st.Pop(); x.GetKind() == stack.Int {
+			st.Push(stack.NewIntValue(-x.GetInt()))
+		} else {
+			st.Push(stack.NewFloatValue(-x.GetFloat()))
+		}
+		return nil
+	}},
+	{"eq", func(st *stack.Stack) error {
+		if err := st.Expect(stack.Any, stack.Any); err != nil {
+			return err
+		}
+		y, x := st.Pop(), st.Pop()
+		st.Push(stack.NewBoolValue(x.Equals(y)))
+		return nil
+	}},
+	{"neq", func(st *stack.Stack) error {
+		if err := st.Expect(stack.Any, stack.Any); err != nil {
+			return err
+		}
+		y, x := st.Pop(), st.Pop()
+		st.Push(stack.NewBoolValue(!x.Equals(y)))
+		return nil
+	}},
+	{"lt", func(st *stack.Stack) error {
+		return compare(st, func(x, y int64) bool { return x < y }, func(x, y float64) bool { return x < y }, func(c int) bool { return c < 0 })
+	}},
+	{"gt", func(st *stack.Stack) error {
+		return compare(st, func(x, y int64) bool { return x > y }, func(x, y float64) bool { return x > y }, func(c int) bool { return c > 0 })
+	}},
+	{"lte", func(st *stack.Stack) error {
+		return compare(st, func(x, y int64) bool { return x <= y }, func(x, y float64) bool { return x <= y }, func(c int) bool { return c <= 0 })
+	}},
+	{"gte", func(st *stack.Stack) error {
+		return compare(st, func(x, y int64) bool { return x >= y }, func(x, y float64) bool { return x >= y }, func(c int) bool { return c >= 0 })
+	}},
+	{"not", func(st *stack.Stack) error {
+		if err := st.Expect(stack.Bool); err != nil {
+			return err
+		}
+		st.Push(stack.NewBoolValue(!st.Pop().GetBool()))
+		return nil
+	}},
+	{"and", func(st *stack.Stack) error {
+		return bitwise(st, func(x, y int64) int64 { return x & y })
+	}},
+	{"or", func(st *stack.Stack) error {
+		return bitwise(st, func(x, y int64) int64 { return x | y })
+	}},
+	{"xor", func(st *stack.Stack) error {
+		return bitwise(st, func(x, y int64) int64 { return x ^ y })
+	}},
+}
+
+func init() {
+	for _, op := range operators {
+		fn := op.fn
+		stdfn[op.name] = func(vm *VelvetVM, st *stack.Stack) error {
+			return fn(st)
+		}
+	}
+}
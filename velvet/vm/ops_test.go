@@ -0,0 +1,72 @@
+package vm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/voidwyrm-2/velvet-vm/velvet/vm/stack"
+)
+
+func TestOperators(t *testing.T) {
+	tests := []struct {
+		operator, operands, expected string
+	}{
+		{"add", "push 2\npush 3", "5"},
+		{"sub", "push 2\npush 3", "-1"},
+		{"mul", "push 2\npush 3", "6"},
+		{"div", "push 7\npush 2", "3"},
+		{"div", "push -7\npush 2", "-3"},
+		{"pow", "push 2\npush 10", "1024"},
+		{"log", "push 1", "0"},
+		{"neg", "push 4", "-4"},
+		{"eq", "push 1\npush 1", "true"},
+		{"neq", "push 1\npush 1", "false"},
+		{"lt", "push 1\npush 2", "true"},
+		{"gt", "push 1\npush 2", "false"},
+		{"lte", "push 2\npush 2", "true"},
+		{"gte", "push 1\npush 2", "false"},
+		{"not", "push true", "false"},
+		{"and", "push 12\npush 10", "8"},
+		{"or", "push 12\npush 10", "14"},
+		{"xor", "push 12\npush 10", "6"},
+	}
+
+	for _, tt := range tests {
+		// the operators can still be called as functions, and have to do the same thing either way
+		for _, form := range []string{tt.operator, "call " + tt.operator} {
+			t.Run(form, func(t *testing.T) {
+				out, res, err := run(t, tt.operands+"\n"+form+"\ncall println\nhalt 0")
+				if err != nil {
+					t.Fatal(err)
+				} else if out != tt.expected+"\n" {
+					t.Errorf("expected %q, but got %q", tt.expected+"\n", out)
+				} else if len(res.Stack) != 0 {
+					t.Errorf("expected the operands to be popped, but the stack is %s", res.Stack.Dump())
+				}
+			})
+		}
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	for _, form := range []string{"div", "call div"} {
+		t.Run(form, func(t *testing.T) {
+			if out := runRejected(t, "push 1\npush 0\n"+form); out != "integer division by zero\n" {
+				t.Errorf("expected the division to be rejected, but got %q", out)
+			}
+		})
+	}
+}
+
+func TestOperatorWrongKinds(t *testing.T) {
+	_, _, err := run(t, "push \"a\"\npush 1\nadd\nhalt 0")
+
+	var re *RuntimeError
+	if !errors.As(err, &re) {
+		t.Fatalf("expected a *RuntimeError, but got %v", err)
+	} else if opcodeName(re.Opcode) != "add" {
+		t.Errorf("expected the error to be from add, but it's from %s", opcodeName(re.Opcode))
+	} else if re.Expected != stack.Number || re.Actual != stack.String {
+		t.Errorf("expected a Number but found a String, but got %v", err)
+	}
+}
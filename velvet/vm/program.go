@@ -0,0 +1,380 @@
+package vm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/voidwyrm-2/velvet-vm/velvet/vm/stack"
+)
+
+const (
+	InstructionSize int = 7
+	infoSize        int = 32
+	signature           = "Velvet Scarlatina"
+)
+
+/*
+Program is bytecode that has been validated and decoded by Load;
+it's never modified after loading, so it can be run by any number of VMs at the same time
+*/
+type Program struct {
+	bytes        []byte
+	isLibrary    bool
+	vars         int
+	dataAddr     int
+	entry        int // the index of the first instruction to execute
+	instructions []instruction
+	imports      []string // the names of the functions the program calls, indexed by instruction.fn
+}
+
+// instruction is an instruction decoded by Load, with its data section values already read
+type instruction struct {
+	opcode   uint16
+	flag     uint8
+	one, two uint16
+	both     uint32
+	branch   bool             // for jumps, if the return address should be pushed
+	target   int              // for jumps, the index of the instruction to jump to
+	fn       int              // for call and push, the index of the function in the import table, or -1 for getErr
+	value    stack.StackValue // for push, the value to push
+}
+
+// Returns the address of the instruction at the given index
+func addrOf(index int) int {
+	return infoSize + index*InstructionSize
+}
+
+// Returns the index of the instruction at the given address
+func indexOf(addr int) (int, bool) {
+	if addr < infoSize || (addr-infoSize)%InstructionSize != 0 {
+		return 0, false
+	}
+	return (addr - infoSize) / InstructionSize, true
+}
+
+/*
+Validates and decodes bytecode so that it can be run with RunProgram
+*/
+func Load(bytes []byte) (*Program, error) {
+	if len(bytes) < infoSize || !strings.HasPrefix(string(bytes), signature) {
+		return nil, errors.New("malformed bytecode format")
+	}
+
+	prog := &Program{
+		bytes:     append([]byte{}, bytes...),
+		isLibrary: (bytes[17] >> 7) == 1,
+		vars:      (int(bytes[18]) << 8) + int(bytes[19]),
+		dataAddr:  (int(bytes[20]) << 24) + (int(bytes[21]) << 16) + (int(bytes[22]) << 8) + int(bytes[23]),
+	}
+
+	entryOffset := (int(bytes[24]) << 24) + (int(bytes[25]) << 16) + (int(bytes[26]) << 8) + int(bytes[27])
+
+	if prog.dataAddr < infoSize || prog.dataAddr > len(bytes) || (prog.dataAddr-infoSize)%InstructionSize != 0 {
+		return nil, fmt.Errorf("data section address '%d' is not valid", prog.dataAddr)
+	}
+
+	prog.entry = entryOffset
+	if entryOffset < 0 || addrOf(entryOffset) >= prog.dataAddr {
+		return nil, errors.New("the start of execution is not inside the instruction section")
+	}
+
+	prog.instructions = make([]instruction, (prog.dataAddr-infoSize)/InstructionSize)
+	importIndexes := map[string]int{}
+	for i := range prog.instructions {
+		if err := prog.decode(i, importIndexes); err != nil {
+			return nil, fmt.Errorf("invalid instruction at %d: %s", addrOf(i), err.Error())
+		}
+	}
+
+	return prog, nil
+}
+
+// Returns true if the program has been declared as a library
+func (p *Program) IsLibrary() bool {
+	return p.isLibrary
+}
+
+// Returns how many variables the program uses
+func (p *Program) Vars() int {
+	return p.vars
+}
+
+// Returns the names of the functions the program uses, in the order they're first used
+func (p *Program) Imports() []string {
+	return append([]string{}, p.imports...)
+}
+
+// Returns the index of the function in the import table, adding it if it's not there yet
+func (p *Program) importIndex(name string, indexes map[string]int) int {
+	if i, ok := indexes[name]; ok {
+		return i
+	}
+
+	indexes[name] = len(p.imports)
+	p.imports = append(p.imports, name)
+	return len(p.imports) - 1
+}
+
+// Decodes the instruction at the given index
+func (p *Program) decode(index int, importIndexes map[string]int) error {
+	raw := p.bytes[addrOf(index) : addrOf(index)+InstructionSize]
+
+	ins := instruction{
+		opcode: (uint16(raw[0]) << 8) + uint16(raw[1]),
+		flag:   raw[2],
+		one:    uint16(raw[3])<<8 + uint16(raw[4]),
+		two:    uint16(raw[5])<<8 + uint16(raw[6]),
+	}
+	ins.both = uint32(ins.one)<<16 + uint32(ins.two)
+
+	switch ins.opcode {
+	case 0, 1, 2, 5, 6, 7, 8, 28, 29: // nop, ret, halt, pop, dup, swap, rot, enter, lset/lget
+	case 3: // call
+		if ins.flag&1 == 0 {
+			if name, err := p.data(ins.one, uint(ins.two)); err != nil {
+				return err
+			} else if string(name) == "getErr" {
+				ins.fn = -1
+			} else {
+				ins.fn = p.importIndex(string(name), importIndexes)
+			}
+		}
+	case 4: // push
+		switch ins.flag {
+		case 1: // bool
+			ins.value = stack.NewBoolValue(ins.one != 0)
+		case 2: // string
+			if str, err := p.data(ins.one, uint(ins.two)); err != nil {
+				return err
+			} else {
+				ins.value = stack.NewStringValue(string(str))
+			}
+		case 3: // list
+			if lb, err := p.data(ins.one, uint(ins.two)*5); err != nil {
+				return err
+			} else if ls, err := makeListFromBytes(lb, p.data); err != nil {
+				return err
+			} else {
+				ins.value = stack.NewListValue(ls...)
+			}
+		case 4: // function
+			if name, err := p.data(ins.one, uint(ins.two)); err != nil {
+				return err
+			} else {
+				ins.fn = p.importIndex(string(name), importIndexes)
+			}
+		case 5: // error register
+		case 6: // bytecode function
+			if target, err := p.target(ins.both); err != nil {
+				return err
+			} else {
+				ins.target = target
+			}
+		case 7: // int64
+			if b, err := p.data(ins.one, 8); err != nil {
+				return err
+			} else {
+				ins.value = stack.NewIntValue(int64(binary.BigEndian.Uint64(b)))
+			}
+		case 8: // float64
+			if b, err := p.data(ins.one, 8); err != nil {
+				return err
+			} else {
+				ins.value = stack.NewFloatValue(math.Float64frombits(binary.BigEndian.Uint64(b)))
+			}
+		case 9: // null
+			ins.value = stack.NewNullValue()
+		case 10: // map
+			if mb, err := p.data(ins.one, uint(ins.two)*10); err != nil {
+				return err
+			} else if m, err := makeMapFromBytes(mb, p.data); err != nil {
+				return err
+			} else {
+				ins.value = stack.NewMapValue(m)
+			}
+		default:
+			ins.value = stack.NewIntValue(int64(int32(ins.both)))
+		}
+	case 9: // set/get
+		if int(ins.one) >= p.vars {
+			return fmt.Errorf("%d is not a valid variable index", ins.one)
+		}
+	case 10: // j/jt/jf/je/jne or br/brt/brf/bre/brne
+		ins.flag, ins.branch = exactIsBranch(ins.flag)
+		if ins.flag > 4 {
+			return fmt.Errorf("invalid jump flag '%d'", ins.flag)
+		}
+
+		if target, err := p.target(ins.both); err != nil {
+			return err
+		} else {
+			ins.target = target
+		}
+	default:
+		if ins.opcode < firstOperator || int(ins.opcode-firstOperator) >= len(operators) {
+			return fmt.Errorf("invalid opcode '%d'", ins.opcode)
+		}
+	}
+
+	p.instructions[index] = ins
+	return nil
+}
+
+// Returns the index of the instruction at the given address
+func (p *Program) target(addr uint32) (int, error) {
+	if target, ok := indexOf(int(addr)); !ok || addrOf(target) >= p.dataAddr {
+		return 0, fmt.Errorf("address '%d' is not the address of an instruction", addr)
+	} else {
+		return target, nil
+	}
+}
+
+// Returns a copy of a constant value so that changing the pushed value doesn't change the program
+func copyConstant(sv stack.StackValue) stack.StackValue {
+	switch sv.GetKind() {
+	case stack.List:
+		items := make([]stack.StackValue, len(sv.GetList()))
+		for i, item := range sv.GetList() {
+			items[i] = copyConstant(item)
+		}
+		return stack.NewListValue(items...)
+	case stack.Map:
+		m := stack.NewValueMap()
+		sv.GetMap().Range(func(key, value stack.StackValue) bool {
+			m.Set(key, copyConstant(value))
+			return true
+		})
+		return stack.NewMapValue(m)
+	}
+	return sv
+}
+
+// Returns the bytes of the data section at the given address
+func (p *Program) data(addr uint16, length uint) ([]byte, error) {
+	if p.dataAddr+int(addr)+int(length) > len(p.bytes) {
+		return []byte{}, fmt.Errorf("data section address '%d' is not valid", addr)
+	}
+	return p.bytes[p.dataAddr+int(addr) : p.dataAddr+int(addr)+int(length)], nil
+}
+
+// the type bytes of list items in the data section, which don't depend on the kinds of stack values
+const (
+	dataInt32   uint8 = 0
+	dataString  uint8 = 1
+	dataBool    uint8 = 2
+	dataList    uint8 = 4
+	dataInt64   uint8 = 16
+	dataFloat64 uint8 = 32
+	dataNull    uint8 = 64
+	dataMap     uint8 = 128
+)
+
+// Makes a map from list items, where the items are the keys and values of the entries one after the other
+func makeMapFromBytes(mb []byte, getBytes func(addr uint16, length uint) ([]byte, error)) (*stack.ValueMap, error) {
+	items, err := makeListFromBytes(mb, getBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	m := stack.NewValueMap()
+	for i := 0; i+1 < len(items); i += 2 {
+		if !items[i].Is(stack.Key) {
+			return nil, fmt.Errorf("a %s can't be a map key", items[i].GetKind().Name())
+		}
+		m.Set(items[i], items[i+1])
+	}
+	return m, nil
+}
+
+func makeListFromBytes(lb []byte, getBytes func(addr uint16, length uint) ([]byte, error)) ([]stack.StackValue, error) {
+	if len(lb) == 0 {
+		return []stack.StackValue{}, nil
+	}
+
+	itemBytes := []struct {
+		kind         uint8
+		addr, length uint16
+	}{}
+
+	for i := 0; i < len(lb); i += 5 {
+		itemBytes = append(itemBytes, struct {
+			kind   uint8
+			addr   uint16
+			length uint16
+		}{
+			kind:   lb[i],
+			addr:   (uint16(lb[i+1]) << 8) + uint16(lb[i+2]),
+			length: (uint16(lb[i+3]) << 8) + uint16(lb[i+4]),
+		})
+	}
+
+	items := []stack.StackValue{}
+
+	for _, it := range itemBytes {
+		switch it.kind {
+		case dataString:
+			if str, err := getBytes(it.addr, uint(it.length)); err != nil {
+				return []stack.StackValue{}, err
+			} else {
+				items = append(items, stack.NewStringValue(string(str)))
+			}
+		case dataBool:
+			if b, err := getBytes(it.addr, 1); err != nil {
+				return []stack.StackValue{}, err
+			} else {
+				items = append(items, stack.NewBoolValue(b[0] == 1))
+			}
+		case dataList:
+			if sublsb, err := getBytes(it.addr, uint(it.length)*5); err != nil {
+				return []stack.StackValue{}, err
+			} else if subls, err := makeListFromBytes(sublsb, getBytes); err != nil {
+				return []stack.StackValue{}, err
+			} else {
+				items = append(items, stack.NewListValue(subls...))
+			}
+		case dataMap:
+			if submb, err := getBytes(it.addr, uint(it.length)*10); err != nil {
+				return []stack.StackValue{}, err
+			} else if subm, err := makeMapFromBytes(submb, getBytes); err != nil {
+				return []stack.StackValue{}, err
+			} else {
+				items = append(items, stack.NewMapValue(subm))
+			}
+		/*case stack.Function:
+		if fnName, err := getBytes(args.one, uint(args.two)); err != nil {
+			return []stack.StackValue, err
+		} else if fn, ok := vm.lookup(string(fnName)); !ok {
+			return []stack.StackValue,fmt.Errorf("function '%s' does not exist", string(fnName))
+		} else {
+			vm.stack.Push(stack.NewFuncValue(fn))
+		}*/
+		case dataInt64:
+			if b, err := getBytes(it.addr, 8); err != nil {
+				return []stack.StackValue{}, err
+			} else {
+				items = append(items, stack.NewIntValue(int64(binary.BigEndian.Uint64(b))))
+			}
+		case dataFloat64:
+			if b, err := getBytes(it.addr, 8); err != nil {
+				return []stack.StackValue{}, err
+			} else {
+				items = append(items, stack.NewFloatValue(math.Float64frombits(binary.BigEndian.Uint64(b))))
+			}
+		case dataNull:
+			items = append(items, stack.NewNullValue())
+		case dataInt32:
+			if b, err := getBytes(it.addr, 4); err != nil {
+				return []stack.StackValue{}, err
+			} else {
+				items = append(items, stack.NewIntValue(int64(int32(binary.BigEndian.Uint32(b)))))
+			}
+		default:
+			return []stack.StackValue{}, fmt.Errorf("invalid list item type '%d'", it.kind)
+		}
+	}
+
+	return items, nil
+}
@@ -0,0 +1,192 @@
+package stack
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"slices"
+)
+
+// the order of the kinds when values of different kinds are compared, numbers are ordered together
+func kindOrder(kind ValueKind) int {
+	switch kind {
+	case Null:
+		return 0
+	case Bool:
+		return 1
+	case Int, Float:
+		return 2
+	case String:
+		return 3
+	case Bytes:
+		return 4
+	case List:
+		return 5
+	case Map:
+		return 6
+	case Function:
+		return 7
+	}
+	return -1
+}
+
+// compares an int and a float exactly, NaN is less than every other number
+func compareIntFloat(i int64, f float64) int {
+	switch {
+	case math.IsNaN(f):
+		return 1
+	case f < math.MinInt64:
+		return 1
+	case f >= math.MaxInt64:
+		return -1
+	}
+
+	t := math.Trunc(f)
+	if c := cmp.Compare(i, int64(t)); c != 0 {
+		return c
+	} else if f > t {
+		return -1
+	} else if f < t {
+		return 1
+	}
+	return 0
+}
+
+/*
+Compares two values with a total order, returning -1, 0 or 1 like cmp.Compare;
+values of different kinds are ordered null, bools, numbers, strings, bytes, lists, maps, then functions,
+Ints and Floats are compared by their value, NaN is less than every other number and equal to itself,
+strings and bytes are compared byte by byte, lists item by item, maps entry by entry in the order of their keys, and functions by identity
+*/
+func (sv StackValue) Compare(other StackValue) int {
+	if c := cmp.Compare(kindOrder(sv.kind), kindOrder(other.kind)); c != 0 {
+		return c
+	}
+
+	switch sv.kind {
+	case Bool:
+		return cmp.Compare(sv.bits, other.bits)
+	case Int:
+		if other.kind == Int {
+			return cmp.Compare(sv.GetInt(), other.GetInt())
+		}
+		return compareIntFloat(sv.GetInt(), other.GetFloat())
+	case Float:
+		if other.kind == Int {
+			return -compareIntFloat(other.GetInt(), sv.GetFloat())
+		}
+		// cmp.Compare orders NaN before every other float
+		return cmp.Compare(sv.GetFloat(), other.GetFloat())
+	case String:
+		return cmp.Compare(sv.GetString(), other.GetString())
+	case Bytes:
+		return bytes.Compare(sv.GetBytes(), other.GetBytes())
+	case List:
+		x, y := sv.GetList(), other.GetList()
+		for i := range min(len(x), len(y)) {
+			if c := x[i].Compare(y[i]); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(x), len(y))
+	case Map:
+		x, y := sortedEntries(sv.GetMap()), sortedEntries(other.GetMap())
+		for i := range min(len(x), len(y)) {
+			if c := x[i].key.Compare(y[i].key); c != 0 {
+				return c
+			} else if c := x[i].value.Compare(y[i].value); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(x), len(y))
+	case Function:
+		return cmp.Compare(uintptr(sv.ptr), uintptr(other.ptr))
+	}
+	return 0
+}
+
+// Returns the entries of a map sorted by their keys, so that maps can be compared without depending on the order keys were set
+func sortedEntries(m *ValueMap) []mapEntry {
+	entries := make([]mapEntry, 0, m.Len())
+	m.Range(func(key, value StackValue) bool {
+		entries = append(entries, mapEntry{key: key, value: value})
+		return true
+	})
+
+	slices.SortFunc(entries, func(a, b mapEntry) int {
+		return a.key.Compare(b.key)
+	})
+	return entries
+}
+
+// Returns true if two values are equal by Compare, so bytes, lists and maps are equal if their items are, and functions are only equal to themselves
+func (sv StackValue) Equals(other StackValue) bool {
+	return sv.Compare(other) == 0
+}
+
+var hashSeed = maphash.MakeSeed()
+
+/*
+Returns a hash of the value, values that are equal always have the same hash;
+hashes are only the same within one run of the host program
+*/
+func (sv StackValue) Hash() uint64 {
+	var h maphash.Hash
+	h.SetSeed(hashSeed)
+	sv.writeHash(&h)
+	return h.Sum64()
+}
+
+func writeUint64(h *maphash.Hash, n uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], n)
+	h.Write(b[:])
+}
+
+func (sv StackValue) writeHash(h *maphash.Hash) {
+	h.WriteByte(byte(kindOrder(sv.kind)))
+
+	switch sv.kind {
+	case Bool:
+		h.WriteByte(byte(sv.bits))
+	case Int:
+		writeUint64(h, uint64(sv.GetInt()))
+	case Float:
+		// Floats that equal an Int have to hash the same as the Int
+		if f := sv.GetFloat(); math.IsNaN(f) {
+			h.WriteByte(0)
+		} else if t := math.Trunc(f); t == f && f >= math.MinInt64 && f < math.MaxInt64 {
+			writeUint64(h, uint64(int64(f)))
+		} else {
+			writeUint64(h, math.Float64bits(f))
+		}
+	case String:
+		h.WriteString(sv.GetString())
+	case Bytes:
+		writeUint64(h, uint64(len(sv.GetBytes())))
+		h.Write(sv.GetBytes())
+	case List:
+		writeUint64(h, uint64(len(sv.GetList())))
+		for _, item := range sv.GetList() {
+			item.writeHash(h)
+		}
+	case Map:
+		// the entries are hashed on their own and summed, so the order keys were set in doesn't change the hash
+		var sum uint64
+		sv.GetMap().Range(func(key, value StackValue) bool {
+			var eh maphash.Hash
+			eh.SetSeed(hashSeed)
+			key.writeHash(&eh)
+			value.writeHash(&eh)
+			sum += eh.Sum64()
+			return true
+		})
+
+		writeUint64(h, uint64(sv.GetMap().Len()))
+		writeUint64(h, sum)
+	case Function:
+		writeUint64(h, uint64(uintptr(sv.ptr)))
+	}
+}
@@ -0,0 +1,127 @@
+package stack
+
+import (
+	"slices"
+)
+
+type mapEntry struct {
+	key, value StackValue
+	deleted    bool
+}
+
+/*
+ValueMap is the value behind a Map value, the entries are kept in the order that their keys were first set;
+keys can be any kind in Key, and are found by their hash and then compared with Equals
+*/
+type ValueMap struct {
+	entries []mapEntry
+	index   map[uint64][]int
+	deleted int
+}
+
+func NewValueMap() *ValueMap {
+	return &ValueMap{entries: []mapEntry{}, index: map[uint64][]int{}}
+}
+
+// returns the index of the key's entry, or -1 if the key isn't in the map
+func (m *ValueMap) find(key StackValue, hash uint64) int {
+	for _, i := range m.index[hash] {
+		if m.entries[i].key.Equals(key) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *ValueMap) Len() int {
+	return len(m.entries) - m.deleted
+}
+
+func (m *ValueMap) Get(key StackValue) (StackValue, bool) {
+	if i := m.find(key, key.Hash()); i >= 0 {
+		return m.entries[i].value, true
+	}
+	return NewNullValue(), false
+}
+
+func (m *ValueMap) Has(key StackValue) bool {
+	return m.find(key, key.Hash()) >= 0
+}
+
+// Sets the value of a key; like with lists, the value can't contain the map itself
+func (m *ValueMap) Set(key, value StackValue) {
+	hash := key.Hash()
+	if i := m.find(key, hash); i >= 0 {
+		m.entries[i].value = value
+		return
+	}
+
+	m.index[hash] = append(m.index[hash], len(m.entries))
+	m.entries = append(m.entries, mapEntry{key: key, value: value})
+}
+
+// Removes the key from the map, returning false if it wasn't in the map
+func (m *ValueMap) Delete(key StackValue) bool {
+	hash := key.Hash()
+	i := m.find(key, hash)
+	if i < 0 {
+		return false
+	}
+
+	m.entries[i] = mapEntry{deleted: true}
+	m.deleted++
+
+	if indexes := slices.DeleteFunc(m.index[hash], func(j int) bool { return j == i }); len(indexes) == 0 {
+		delete(m.index, hash)
+	} else {
+		m.index[hash] = indexes
+	}
+
+	// deleted entries are only kept until they're most of the map
+	if m.deleted > len(m.entries)/2 {
+		m.compact()
+	}
+
+	return true
+}
+
+func (m *ValueMap) compact() {
+	entries := m.entries
+	m.entries, m.index, m.deleted = make([]mapEntry, 0, len(entries)-m.deleted), map[uint64][]int{}, 0
+
+	for _, e := range entries {
+		if !e.deleted {
+			m.Set(e.key, e.value)
+		}
+	}
+}
+
+// Returns the keys of the map in the order they were first set
+func (m *ValueMap) Keys() []StackValue {
+	keys := make([]StackValue, 0, m.Len())
+	for _, e := range m.entries {
+		if !e.deleted {
+			keys = append(keys, e.key)
+		}
+	}
+	return keys
+}
+
+// Calls fn with each entry of the map in the order their keys were first set, stopping if fn returns false
+func (m *ValueMap) Range(fn func(key, value StackValue) bool) {
+	for _, e := range m.entries {
+		if !e.deleted && !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// Returns a copy of the map, the keys and values themselves aren't copied
+func (m *ValueMap) Clone() *ValueMap {
+	clone := NewValueMap()
+	m.Range(func(key, value StackValue) bool {
+		clone.Set(key, value)
+		return true
+	})
+	return clone
+}
@@ -2,7 +2,6 @@ package stack
 
 import (
 	"fmt"
-	"os"
 	"strings"
 )
 
@@ -38,31 +37,39 @@ func (s *Stack) Pop() StackValue {
 
 func (s *Stack) TryPop() (StackValue, bool) {
 	if s.Empty() {
-		return StackValue{}, false
+		return NewNullValue(), false
 	}
 	return s.Pop(), true
 }
 
-func (s Stack) ExpectErr(kinds ...ValueKind) error {
+// ExpectError is returned when the values on top of the stack don't match what was expected
+type ExpectError struct {
+	Expected, Actual ValueKind
+	Underflow        bool
+}
+
+func (ee *ExpectError) Error() string {
+	if ee.Underflow {
+		return fmt.Sprintf("expected '%s' on the stack, but the stack is not large enough", ee.Expected.Name())
+	}
+	return fmt.Sprintf("expected '%s' on the stack, but found '%s' instead", ee.Expected.Name(), ee.Actual.Name())
+}
+
+/*
+Checks that the top of the stack holds the given kinds, where the last kind is the top of the stack;
+the returned error is always an *ExpectError
+*/
+func (s Stack) Expect(kinds ...ValueKind) error {
 	if len(kinds) > len(s) {
-		return fmt.Errorf("expected '%s' on the stack, but the stack is not large enough", kinds[len(kinds)-1].Name())
+		return &ExpectError{Expected: kinds[len(kinds)-len(s)-1], Underflow: true}
 	}
 
-	ki, si := 0, len(s)-1
+	offset := len(s) - len(kinds)
 
-	for ki < len(kinds) {
-		if !s[si].Is(kinds[ki]) && kinds[ki] == Any {
-			return fmt.Errorf("expected '%s' on the stack, but found '%s' instead", kinds[ki].Name(), s[si].GetKind().Name())
+	for ki, kind := range kinds {
+		if !s[offset+ki].Is(kind) {
+			return &ExpectError{Expected: kind, Actual: s[offset+ki].GetKind()}
 		}
-		ki += 1
-		si -= 1
 	}
 	return nil
 }
-
-func (s Stack) Expect(kinds ...ValueKind) {
-	if err := s.ExpectErr(kinds...); err != nil {
-		fmt.Println(err.Error())
-		os.Exit(1)
-	}
-}
@@ -0,0 +1,25 @@
+package stack
+
+import "testing"
+
+// Pushes and pops 64 copies of a value per iteration
+func benchmarkPushPop(b *testing.B, value StackValue) {
+	b.ReportAllocs()
+	st := New()
+	for range b.N {
+		for range 64 {
+			st.Push(value)
+		}
+		for range 64 {
+			st.Pop()
+		}
+	}
+}
+
+func BenchmarkPushPopNumber(b *testing.B) {
+	benchmarkPushPop(b, NewNumberValue(1))
+}
+
+func BenchmarkPushPopString(b *testing.B) {
+	benchmarkPushPop(b, NewStringValue("hello"))
+}
@@ -2,132 +2,338 @@ package stack
 
 import (
 	"fmt"
+	"math"
 	"strings"
+	"unsafe"
 )
 
 type ValueKind int16
 
+/*
+Every kind has its own bit, so kinds can be combined into a mask that matches any of them
+*/
 const (
-	Any                = -1
-	Number   ValueKind = 0b0
-	String             = 0b1
-	Bool               = 0b10
-	List               = 0b100
-	Function           = 0b1000
+	String   ValueKind = 0b1
+	Bool     ValueKind = 0b10
+	List     ValueKind = 0b100
+	Function ValueKind = 0b1000
+	Int      ValueKind = 0b10000
+	Float    ValueKind = 0b100000
+	Null     ValueKind = 0b1000000
+	Map      ValueKind = 0b10000000
+	Bytes    ValueKind = 0b100000000
+
+	// Number matches both Ints and Floats
+	Number = Int | Float
+	// Key matches the kinds that can be map keys, which are the kinds that can't be changed
+	Key = Null | Bool | Number | String | Function
+	// Any matches every kind of value
+	Any = String | Bool | List | Function | Int | Float | Null | Map | Bytes
 )
 
+var kindNames = map[ValueKind]string{Any: "Any", Number: "Number", Key: "Key", String: "String", Bool: "Bool", List: "List", Function: "Function", Int: "Int", Float: "Float", Null: "Null", Map: "Map", Bytes: "Bytes"}
+
 func (vk ValueKind) Name() string {
-	return map[ValueKind]string{Any: "Any", Number: "Number", String: "String", Bool: "Bool", List: "List", Function: "Function"}[vk]
+	if name, ok := kindNames[vk]; ok {
+		return name
+	}
+
+	names := []string{}
+	if vk&Number == Number {
+		names = append(names, kindNames[Number])
+		vk &^= Number
+	}
+
+	for _, kind := range []ValueKind{Int, Float, String, Bytes, Bool, List, Map, Function, Null} {
+		if vk&kind != 0 {
+			names = append(names, kindNames[kind])
+		}
+	}
+	return strings.Join(names, "|")
 }
 
+/*
+StackValue is a tagged union of every kind of value;
+numbers and bools are stored in bits, and strings, bytes, lists, maps and functions are stored behind ptr, which keeps the value at three words
+
+The zero StackValue has no kind and isn't a valid value, NewNullValue should be used for a value that isn't set
+*/
 type StackValue struct {
-	numVal    float32
-	stringVal string
-	listVal   []StackValue
-	boolVal   bool
-	funcVal   func(st *Stack) error
-	kind      ValueKind
+	ptr  unsafe.Pointer // the bytes of a string, a *[]byte, a *[]StackValue, a *ValueMap or a *funcValue
+	bits uint64         // the bits of an int, a float, a bool, or the length of a string
+	kind ValueKind
 }
 
+func NewNullValue() StackValue {
+	return StackValue{kind: Null}
+}
+
+func NewIntValue(value int64) StackValue {
+	return StackValue{kind: Int, bits: uint64(value)}
+}
+
+func NewFloatValue(value float64) StackValue {
+	return StackValue{kind: Float, bits: math.Float64bits(value)}
+}
+
+// Creates a Float, numbers used to only be float32 and this is kept for code that still uses them
 func NewNumberValue(value float32) StackValue {
-	return StackValue{kind: Number, numVal: value}
+	return NewFloatValue(float64(value))
 }
 
 func NewBoolValue(value bool) StackValue {
-	return StackValue{kind: Bool, boolVal: value}
+	if value {
+		return StackValue{kind: Bool, bits: 1}
+	}
+	return StackValue{kind: Bool}
 }
 
 func NewStringValue(value string) StackValue {
-	return StackValue{kind: String, stringVal: value}
+	return StackValue{kind: String, ptr: unsafe.Pointer(unsafe.StringData(value)), bits: uint64(len(value))}
+}
+
+// Creates a Bytes value, which refers to the slice, so setting a byte of the value changes the slice
+func NewBytesValue(value []byte) StackValue {
+	return StackValue{kind: Bytes, ptr: unsafe.Pointer(&value)}
+}
+
+func NewMapValue(value *ValueMap) StackValue {
+	return StackValue{kind: Map, ptr: unsafe.Pointer(value)}
 }
 
 func NewListValue(values ...StackValue) StackValue {
-	return StackValue{kind: List, listVal: values}
+	return StackValue{kind: List, ptr: unsafe.Pointer(&values)}
 }
 
+// Creates a list of the given size where each item is Null
 func AllocListValue(size int) StackValue {
-	return StackValue{kind: List, listVal: make([]StackValue, size)}
+	items := make([]StackValue, size)
+	for i := range items {
+		items[i] = NewNullValue()
+	}
+	return NewListValue(items...)
 }
 
+// Creates a list of the given size where each item is the given value
 func AllocInitListValue(size int, value StackValue) StackValue {
-	l := AllocListValue(size)
-	for range size {
-		l.listVal = append(l.listVal, value)
+	items := make([]StackValue, size)
+	for i := range items {
+		items[i] = value
 	}
-	return l
+	return NewListValue(items...)
+}
+
+// the Go function behind a function value, and where the bytecode starts if it's a bytecode function
+type funcValue struct {
+	call  func(st *Stack) error
+	owner any
+	addr  int
 }
 
 func NewFuncValue(value func(st *Stack) error) StackValue {
-	return StackValue{kind: Function, funcVal: value}
+	return StackValue{kind: Function, ptr: unsafe.Pointer(&funcValue{call: value})}
+}
+
+/*
+Creates a function value that refers to bytecode starting at the given address;
+call is used when the function is called from Go, and owner is what the VM uses to tell which program the address belongs to
+*/
+func NewBytecodeFuncValue(owner any, addr int, call func(st *Stack) error) StackValue {
+	return StackValue{kind: Function, ptr: unsafe.Pointer(&funcValue{call: call, owner: owner, addr: addr})}
 }
 
 func (sv StackValue) Dump() string {
-	return fmt.Sprintf("{%s, '%s', %f, %v}", sv.kind.Name(), sv.stringVal, sv.numVal, sv.boolVal)
+	return fmt.Sprintf("{%s, '%s', %f, %v}", sv.kind.Name(), sv.GetString(), sv.GetFloat(), sv.GetBool())
 }
 
+// Returns true if the value's kind is one of the kinds in the given mask
 func (sv StackValue) Is(kind ValueKind) bool {
-	return sv.kind&kind == sv.kind || Any&kind == Any
+	return sv.kind&kind != 0
 }
 
 func (sv StackValue) GetKind() ValueKind {
 	return sv.kind
 }
 
+// Returns the value of an Int, or of a Float truncated towards zero
+func (sv StackValue) GetInt() int64 {
+	switch sv.kind {
+	case Int:
+		return int64(sv.bits)
+	case Float:
+		return int64(math.Float64frombits(sv.bits))
+	}
+	return 0
+}
+
+// Returns the value of a Float, or of an Int converted to a float
+func (sv StackValue) GetFloat() float64 {
+	switch sv.kind {
+	case Int:
+		return float64(int64(sv.bits))
+	case Float:
+		return math.Float64frombits(sv.bits)
+	}
+	return 0
+}
+
+// Returns the value of an Int or a Float as a float32, numbers used to only be float32 and this is kept for code that still uses them
 func (sv StackValue) GetNum() float32 {
-	return sv.numVal
+	return float32(sv.GetFloat())
 }
 
 func (sv StackValue) GetString() string {
-	return sv.stringVal
+	if sv.kind != String || sv.ptr == nil {
+		return ""
+	}
+	return unsafe.String((*byte)(sv.ptr), int(sv.bits))
+}
+
+func (sv StackValue) GetBytes() []byte {
+	if sv.kind != Bytes {
+		return nil
+	}
+	return *(*[]byte)(sv.ptr)
 }
 
 func (sv StackValue) GetBool() bool {
-	return sv.boolVal
+	return sv.kind == Bool && sv.bits == 1
 }
 
 func (sv StackValue) GetList() []StackValue {
-	return sv.listVal
+	if sv.kind != List {
+		return nil
+	}
+	return *(*[]StackValue)(sv.ptr)
+}
+
+/*
+Replaces the items of a list, lists are references so every copy of the value sees the new items;
+it does nothing if the value isn't a list.
+The items can't contain the list itself (which can be checked with Contains), since formatting, comparing and hashing would never finish
+*/
+func (sv StackValue) SetList(items []StackValue) {
+	if sv.kind == List {
+		*(*[]StackValue)(sv.ptr) = items
+	}
+}
+
+func (sv StackValue) GetMap() *ValueMap {
+	if sv.kind != Map {
+		return nil
+	}
+	return (*ValueMap)(sv.ptr)
+}
+
+/*
+Returns true if the value is the given list or map, or if the given list or map is somewhere inside the lists and maps it holds;
+lists and maps are references, so adding a value that contains a list or map to it would make it contain itself
+*/
+func (sv StackValue) Contains(container StackValue) bool {
+	if !sv.Is(List|Map) || !container.Is(List|Map) {
+		return false
+	}
+	return sv.contains(container.ptr, map[unsafe.Pointer]bool{})
+}
+
+// walks the lists and maps inside of a value, skipping ones that have already been walked
+func (sv StackValue) contains(target unsafe.Pointer, seen map[unsafe.Pointer]bool) bool {
+	if !sv.Is(List|Map) || seen[sv.ptr] {
+		return false
+	} else if sv.ptr == target {
+		return true
+	}
+	seen[sv.ptr] = true
+
+	if sv.kind == List {
+		for _, item := range sv.GetList() {
+			if item.contains(target, seen) {
+				return true
+			}
+		}
+		return false
+	}
+
+	found := false
+	sv.GetMap().Range(func(_, value StackValue) bool {
+		found = value.contains(target, seen)
+		return !found
+	})
+	return found
 }
 
 func (sv StackValue) GetFunc() func(st *Stack) error {
-	return sv.funcVal
+	if sv.kind != Function {
+		return nil
+	}
+	return (*funcValue)(sv.ptr).call
+}
+
+// Returns the address of the bytecode a function value starts at, if it's a bytecode function with the given owner
+func (sv StackValue) GetBytecodeAddr(owner any) (int, bool) {
+	if sv.kind != Function || owner == nil {
+		return 0, false
+	}
+
+	fv := (*funcValue)(sv.ptr)
+	return fv.addr, fv.owner == owner
 }
 
 func (sv StackValue) GetAny() any {
 	switch sv.kind {
-	case Number:
-		return sv.GetNum()
+	case Int:
+		return sv.GetInt()
+	case Float:
+		return sv.GetFloat()
 	case String:
 		return sv.GetString()
+	case Bytes:
+		return sv.GetBytes()
 	case Bool:
 		return sv.GetBool()
 	case List:
 		return sv.GetList()
+	case Map:
+		return sv.GetMap()
 	case Function:
 		return sv.GetFunc()
 	}
-	panic("unreachable")
+	return nil
 }
 
 func (sv StackValue) Format() string {
-	if sv.kind == Function {
+	switch sv.kind {
+	case Function:
 		return "<Function>"
-	} else if sv.kind == List {
+	case Null:
+		return "null"
+	case Bytes:
+		return fmt.Sprintf("<% x>", sv.GetBytes())
+	case List:
 		fi := []string{}
 
 		for _, item := range sv.GetList() {
-			if item.Is(String) {
-				fi = append(fi, "\""+item.Format()+"\"")
-			} else {
-				fi = append(fi, item.Format())
-			}
+			fi = append(fi, item.formatItem())
 		}
 
 		return fmt.Sprintf("[ %s ]", strings.Join(fi, " "))
+	case Map:
+		fi := []string{}
+
+		sv.GetMap().Range(func(key, value StackValue) bool {
+			fi = append(fi, key.formatItem(), value.formatItem())
+			return true
+		})
+
+		return fmt.Sprintf("{ %s }", strings.Join(fi, " "))
 	}
 	return fmt.Sprintf("%v", sv.GetAny())
 }
 
-func (sv StackValue) Equals(other StackValue) bool {
-	return sv.GetAny() == sv.GetAny()
+// formats a value inside of a list or map, where strings are quoted
+func (sv StackValue) formatItem() string {
+	if sv.Is(String) {
+		return "\"" + sv.Format() + "\""
+	}
+	return sv.Format()
 }
@@ -4,248 +4,863 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"math"
-	"os"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/voidwyrm-2/velvet-vm/velvet/vm/stack"
 )
 
-var stdfn = map[string]func(st *stack.Stack) error{
-	"error": func(st *stack.Stack) error {
+// ErrEOF is the error the read functions set the error flag with when there's no more input
+var ErrEOF = errors.New("eof")
+
+/*
+Reads a line from the VM's input, without the line ending;
+the last line doesn't need a line ending, and ErrEOF is returned once there's nothing left to read
+*/
+func (vm *VelvetVM) readLine() (string, error) {
+	line := []byte{}
+
+	for {
+		frag, err := vm.reader().ReadSlice('\n')
+		line = append(line, frag...)
+
+		if err := vm.CheckStringBytes(len(line)); err != nil {
+			return "", err
+		}
+
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		} else if errors.Is(err, io.EOF) {
+			if len(line) == 0 {
+				return "", ErrEOF
+			}
+			break
+		} else if err != nil {
+			return "", err
+		}
+
+		break
+	}
+
+	return strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r"), nil
+}
+
+/*
+Calls a function value with the given arguments on the stack, then pops the value it returns;
+the function can be a Go function or bytecode that the VM runs until it returns
+*/
+func callFunc(st *stack.Stack, fn func(st *stack.Stack) error, result stack.ValueKind, args ...stack.StackValue) (stack.StackValue, error) {
+	for _, arg := range args {
+		st.Push(arg)
+	}
+
+	if err := fn(st); err != nil {
+		return stack.NewNullValue(), err
+	} else if err := st.Expect(result); err != nil {
+		return stack.NewNullValue(), err
+	}
+
+	return st.Pop(), nil
+}
+
+// Parses a number, whole numbers are parsed as Ints and everything else as Floats
+func parseNumber(s string) (stack.StackValue, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return stack.NewIntValue(n), nil
+	} else if num, err := strconv.ParseFloat(s, 64); err == nil {
+		return stack.NewFloatValue(num), nil
+	}
+	return stack.NewNullValue(), fmt.Errorf("'%s' is not a number", s)
+}
+
+var stdfn = map[string]func(vm *VelvetVM, st *stack.Stack) error{
+	"error": func(vm *VelvetVM, st *stack.Stack) error {
 		return errors.New("")
 	},
-	"reset": func(st *stack.Stack) error {
+	"reset": func(vm *VelvetVM, st *stack.Stack) error {
+		vm.errFlag = false
+		vm.errReg = ""
 		return nil
 	},
 
-	// operator functions
-	"eq": func(st *stack.Stack) error {
-		st.Expect(stack.Any, stack.Any)
-		y, x := st.Pop(), st.Pop()
-		st.Push(stack.NewBoolValue(x.Equals(y)))
+	// type functions
+	"typeof": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Any); err != nil {
+			return err
+		}
+		st.Push(stack.NewStringValue(st.Pop().GetKind().Name()))
 		return nil
 	},
-	"neq": func(st *stack.Stack) error {
-		st.Expect(stack.Any, stack.Any)
-		y, x := st.Pop(), st.Pop()
-		st.Push(stack.NewBoolValue(!x.Equals(y)))
+	"isNull": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Any); err != nil {
+			return err
+		}
+		st.Push(stack.NewBoolValue(st.Pop().Is(stack.Null)))
 		return nil
 	},
-	"not": func(st *stack.Stack) error {
-		st.Expect(stack.Bool)
-		st.Push(stack.NewBoolValue(!st.Pop().GetBool()))
+	// end type functions
+
+	// IO functions
+	"print": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Any); err != nil {
+			return err
+		}
+		fmt.Fprint(vm.stdout, st.Pop().Format())
+		return nil
+	},
+	"println": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Any); err != nil {
+			return err
+		}
+		fmt.Fprintln(vm.stdout, st.Pop().Format())
+		return nil
+	},
+	"printf": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.String, stack.List); err != nil {
+			return err
+		}
+
+		args, format := st.Pop().GetList(), st.Pop().GetString()
+		if str, err := sprintf(format, args); err != nil {
+			return err
+		} else {
+			fmt.Fprint(vm.stdout, str)
+		}
+
 		return nil
 	},
-	"lt": func(st *stack.Stack) error {
-		st.Expect(stack.Number, stack.Number)
-		y, x := st.Pop(), st.Pop()
-		st.Push(stack.NewBoolValue(x.GetNum() < y.GetNum()))
+	"sprintf": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.String, stack.List); err != nil {
+			return err
+		}
+
+		args, format := st.Pop().GetList(), st.Pop().GetString()
+		if str, err := sprintf(format, args); err != nil {
+			return err
+		} else if err := vm.CheckStringBytes(len(str)); err != nil {
+			return err
+		} else {
+			st.Push(stack.NewStringValue(str))
+		}
+
 		return nil
 	},
-	"gt": func(st *stack.Stack) error {
-		st.Expect(stack.Number, stack.Number)
-		y, x := st.Pop(), st.Pop()
-		st.Push(stack.NewBoolValue(x.GetNum() > y.GetNum()))
+	"putc": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Int); err != nil {
+			return err
+		}
+		fmt.Fprint(vm.stdout, string(rune(st.Pop().GetInt())))
 		return nil
 	},
-	"lte": func(st *stack.Stack) error {
-		st.Expect(stack.Number, stack.Number)
-		y, x := st.Pop(), st.Pop()
-		st.Push(stack.NewBoolValue(x.GetNum() <= y.GetNum()))
+	"putcln": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Int); err != nil {
+			return err
+		}
+		fmt.Fprintln(vm.stdout, string(rune(st.Pop().GetInt())))
 		return nil
 	},
-	"gte": func(st *stack.Stack) error {
-		st.Expect(stack.Number, stack.Number)
-		y, x := st.Pop(), st.Pop()
-		st.Push(stack.NewBoolValue(x.GetNum() >= y.GetNum()))
+	"eprint": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Any); err != nil {
+			return err
+		}
+		fmt.Fprint(vm.stderr, st.Pop().Format())
 		return nil
 	},
-	"add": func(st *stack.Stack) error {
-		st.Expect(stack.Number, stack.Number)
-		y, x := st.Pop(), st.Pop()
-		st.Push(stack.NewNumberValue(x.GetNum() + y.GetNum()))
+	"eprintln": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Any); err != nil {
+			return err
+		}
+		fmt.Fprintln(vm.stderr, st.Pop().Format())
 		return nil
 	},
-	"sub": func(st *stack.Stack) error {
-		st.Expect(stack.Number, stack.Number)
-		y, x := st.Pop(), st.Pop()
-		st.Push(stack.NewNumberValue(x.GetNum() - y.GetNum()))
+	"readn": func(vm *VelvetVM, st *stack.Stack) error {
+		line, err := vm.readLine()
+		if err != nil {
+			return err
+		}
+
+		if num, err := parseNumber(line); err != nil {
+			return err
+		} else {
+			st.Push(num)
+		}
+
 		return nil
 	},
-	"mul": func(st *stack.Stack) error {
-		st.Expect(stack.Number, stack.Number)
-		y, x := st.Pop(), st.Pop()
-		st.Push(stack.NewNumberValue(x.GetNum() * y.GetNum()))
+	"readt": func(vm *VelvetVM, st *stack.Stack) error {
+		line, err := vm.readLine()
+		if err != nil {
+			return err
+		}
+
+		st.Push(stack.NewStringValue(line))
+
 		return nil
 	},
-	"div": func(st *stack.Stack) error {
-		st.Expect(stack.Number, stack.Number)
-		y, x := st.Pop(), st.Pop()
-		st.Push(stack.NewNumberValue(x.GetNum() / y.GetNum()))
+	"readb": func(vm *VelvetVM, st *stack.Stack) error {
+		line, err := vm.readLine()
+		if err != nil {
+			return err
+		}
+
+		st.Push(stack.NewBytesValue([]byte(line)))
+
 		return nil
 	},
-	"pow": func(st *stack.Stack) error {
-		st.Expect(stack.Number, stack.Number)
-		y, x := st.Pop(), st.Pop()
-		st.Push(stack.NewNumberValue(float32(math.Pow(float64(x.GetNum()), float64(y.GetNum())))))
+	"readBytes": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Int); err != nil {
+			return err
+		}
+
+		n := int(st.Pop().GetInt())
+		if n < 0 {
+			return errors.New("cannot read a negative amount of bytes")
+		} else if err := vm.CheckStringBytes(n); err != nil {
+			return err
+		}
+
+		// less than n bytes are only returned at the end of the input
+		b := make([]byte, n)
+		read, err := io.ReadFull(vm.reader(), b)
+		if errors.Is(err, io.EOF) {
+			return ErrEOF
+		} else if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+
+		st.Push(stack.NewBytesValue(b[:read]))
+
 		return nil
 	},
-	"log": func(st *stack.Stack) error {
-		st.Expect(stack.Number)
-		st.Push(stack.NewNumberValue(float32(math.Log(float64(st.Pop().GetNum())))))
+	"writeb": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Bytes); err != nil {
+			return err
+		}
+		_, err := vm.stdout.Write(st.Pop().GetBytes())
+		return err
+	},
+	"readc": func(vm *VelvetVM, st *stack.Stack) error {
+		ch, _, err := vm.reader().ReadRune()
+		if errors.Is(err, io.EOF) {
+			return ErrEOF
+		} else if err != nil {
+			return err
+		}
+
+		st.Push(stack.NewIntValue(int64(ch)))
+
 		return nil
 	},
-	"and": func(st *stack.Stack) error {
-		st.Expect(stack.Number, stack.Number)
-		y, x := st.Pop(), st.Pop()
-		st.Push(stack.NewNumberValue(float32(int(x.GetNum()) & int(y.GetNum()))))
+	// end IO functions
+
+	// string operations
+	"strip": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.String); err != nil {
+			return err
+		}
+		st.Push(stack.NewStringValue(strings.TrimSpace(st.Pop().GetString())))
 		return nil
 	},
-	"or": func(st *stack.Stack) error {
-		st.Expect(stack.Number, stack.Number)
-		y, x := st.Pop(), st.Pop()
-		st.Push(stack.NewNumberValue(float32(int(x.GetNum()) | int(y.GetNum()))))
+	"split": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.String, stack.String); err != nil {
+			return err
+		}
+
+		y, x := st.Pop().GetString(), st.Pop().GetString()
+		parts := strings.Split(x, y)
+		if err := vm.CheckListLength(len(parts)); err != nil {
+			return err
+		}
+
+		l := []stack.StackValue{}
+		for _, v := range parts {
+			l = append(l, stack.NewStringValue(v))
+		}
+
+		st.Push(stack.NewListValue(l...))
 		return nil
 	},
-	"xor": func(st *stack.Stack) error {
-		st.Expect(stack.Number, stack.Number)
-		y, x := st.Pop(), st.Pop()
-		st.Push(stack.NewNumberValue(float32(int(x.GetNum()) ^ int(y.GetNum()))))
+	"substr": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.String, stack.Int, stack.Int); err != nil {
+			return err
+		}
+
+		end, start, str := st.Pop().GetInt(), st.Pop().GetInt(), st.Pop().GetString()
+		if start < 0 || end < start || end > int64(len(str)) {
+			return fmt.Errorf("substring %d:%d is out of range of a string of length %d", start, end, len(str))
+		}
+
+		st.Push(stack.NewStringValue(str[start:end]))
 		return nil
 	},
-	// end operator functions
+	"find": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.String, stack.String); err != nil {
+			return err
+		}
 
-	// IO functions
-	"print": func(st *stack.Stack) error {
-		st.Expect(stack.Any)
-		fmt.Print(st.Pop().Format())
+		// strings that aren't found have an index of -1
+		sub, str := st.Pop().GetString(), st.Pop().GetString()
+		st.Push(stack.NewIntValue(int64(strings.Index(str, sub))))
 		return nil
 	},
-	"println": func(st *stack.Stack) error {
-		st.Expect(stack.Any)
-		fmt.Printf("%v\n", st.Pop().Format())
+	"replace": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.String, stack.String, stack.String); err != nil {
+			return err
+		}
+
+		with, old, str := st.Pop().GetString(), st.Pop().GetString(), st.Pop().GetString()
+
+		// an empty string matches before every character and at the end
+		matches := strings.Count(str, old)
+		if err := vm.CheckStringBytes(len(str) + matches*(len(with)-len(old))); err != nil {
+			return err
+		}
+
+		st.Push(stack.NewStringValue(strings.ReplaceAll(str, old, with)))
 		return nil
 	},
-	"putc": func(st *stack.Stack) error {
-		st.Expect(stack.Number)
-		fmt.Print(string(rune(int(st.Pop().GetNum()))))
+	"upper": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.String); err != nil {
+			return err
+		}
+		st.Push(stack.NewStringValue(strings.ToUpper(st.Pop().GetString())))
 		return nil
 	},
-	"putcln": func(st *stack.Stack) error {
-		st.Expect(stack.Number)
-		fmt.Println(string(rune(int(st.Pop().GetNum()))))
+	"lower": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.String); err != nil {
+			return err
+		}
+		st.Push(stack.NewStringValue(strings.ToLower(st.Pop().GetString())))
 		return nil
 	},
-	"readn": func(st *stack.Stack) error {
-		scanner := bufio.NewScanner(os.Stdin)
-		scanner.Scan()
-		if err := scanner.Err(); err != nil {
+	"startsWith": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.String, stack.String); err != nil {
 			return err
 		}
 
-		if num, err := strconv.ParseFloat(scanner.Text(), 32); err != nil {
+		prefix, str := st.Pop().GetString(), st.Pop().GetString()
+		st.Push(stack.NewBoolValue(strings.HasPrefix(str, prefix)))
+		return nil
+	},
+	"endsWith": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.String, stack.String); err != nil {
 			return err
-		} else {
-			st.Push(stack.NewNumberValue(float32(num)))
 		}
 
+		suffix, str := st.Pop().GetString(), st.Pop().GetString()
+		st.Push(stack.NewBoolValue(strings.HasSuffix(str, suffix)))
 		return nil
 	},
-	"readt": func(st *stack.Stack) error {
-		scanner := bufio.NewScanner(os.Stdin)
-		scanner.Scan()
-		if err := scanner.Err(); err != nil {
+	"join": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.List, stack.String); err != nil {
 			return err
 		}
 
-		st.Push(stack.NewStringValue(scanner.Text()))
+		sep, items := st.Pop().GetString(), st.Pop().GetList()
+
+		parts := make([]string, len(items))
+		size := len(sep) * max(len(items)-1, 0)
+		for i, item := range items {
+			if !item.Is(stack.String) {
+				return fmt.Errorf("item %d of the list is a %s, not a String", i, item.GetKind().Name())
+			}
+			parts[i] = item.GetString()
+			size += len(parts[i])
+		}
+
+		if err := vm.CheckStringBytes(size); err != nil {
+			return err
+		}
 
+		st.Push(stack.NewStringValue(strings.Join(parts, sep)))
 		return nil
 	},
-	"readb": func(st *stack.Stack) error {
-		scanner := bufio.NewScanner(os.Stdin)
-		scanner.Scan()
-		if err := scanner.Err(); err != nil {
+	"repeat": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.String, stack.Int); err != nil {
 			return err
 		}
 
-		b := []stack.StackValue{}
-		for _, byte := range scanner.Bytes() {
-			b = append(b, stack.NewNumberValue(float32(byte)))
+		count, str := st.Pop().GetInt(), st.Pop().GetString()
+		if count < 0 {
+			return errors.New("cannot repeat a string a negative amount of times")
+		} else if len(str) > 0 && count > math.MaxInt/int64(len(str)) {
+			return errors.New("the repeated string would be too large")
+		} else if err := vm.CheckStringBytes(len(str) * int(count)); err != nil {
+			return err
 		}
 
-		st.Push(stack.NewListValue(b...))
-
+		st.Push(stack.NewStringValue(strings.Repeat(str, int(count))))
 		return nil
 	},
-	"readc": func(st *stack.Stack) error {
-		scanner := bufio.NewScanner(os.Stdin)
-		scanner.Scan()
-		if err := scanner.Err(); err != nil {
+	"chars": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.String); err != nil {
 			return err
 		}
 
-		st.Push(stack.NewNumberValue(float32(scanner.Bytes()[0])))
+		// the string is split into its UTF-8 characters, not its bytes
+		str := st.Pop().GetString()
+		if err := vm.CheckListLength(utf8.RuneCountInString(str)); err != nil {
+			return err
+		}
 
+		chars := []stack.StackValue{}
+		for _, ch := range str {
+			chars = append(chars, stack.NewStringValue(string(ch)))
+		}
+
+		st.Push(stack.NewListValue(chars...))
 		return nil
 	},
-	// end IO functions
+	"toString": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Any); err != nil {
+			return err
+		}
 
-	// string operations
-	"strip": func(st *stack.Stack) error {
-		st.Expect(stack.String)
-		st.Push(stack.NewStringValue(strings.TrimSpace(st.Pop().GetString())))
+		str := st.Pop().Format()
+		if err := vm.CheckStringBytes(len(str)); err != nil {
+			return err
+		}
+
+		st.Push(stack.NewStringValue(str))
 		return nil
 	},
-	"split": func(st *stack.Stack) error {
-		st.Expect(stack.String, stack.String)
+	"parseNumber": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.String); err != nil {
+			return err
+		}
 
-		y, x := st.Pop().GetString(), st.Pop().GetString()
-		l := []stack.StackValue{}
-		for _, v := range strings.Split(x, y) {
-			l = append(l, stack.NewStringValue(v))
+		if num, err := parseNumber(strings.TrimSpace(st.Pop().GetString())); err != nil {
+			return err
+		} else {
+			st.Push(num)
 		}
 
-		st.Push(stack.NewListValue(l...))
 		return nil
 	},
 	// end string operations
 
 	// seqence operations
-	"allocList": func(st *stack.Stack) error {
-		st.Expect(stack.Number)
-		st.Push(stack.AllocListValue(int(st.Pop().GetNum())))
+	"allocList": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Int); err != nil {
+			return err
+		}
+		size := int(st.Pop().GetInt())
+		if size < 0 {
+			return errors.New("list size cannot be negative")
+		} else if err := vm.CheckListLength(size); err != nil {
+			return err
+		}
+
+		st.Push(stack.AllocListValue(size))
 		return nil
 	},
-	"allocInitList": func(st *stack.Stack) error {
-		st.Expect(stack.Number, stack.Any)
-		y, x := st.Pop(), int(st.Pop().GetNum())
+	"allocInitList": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Int, stack.Any); err != nil {
+			return err
+		}
+		y, x := st.Pop(), int(st.Pop().GetInt())
+		if x < 0 {
+			return errors.New("list size cannot be negative")
+		} else if err := vm.CheckListLength(x); err != nil {
+			return err
+		}
+
 		st.Push(stack.AllocInitListValue(x, y))
 		return nil
 	},
-	"len": func(st *stack.Stack) error {
-		st.Expect(stack.List | stack.String)
+	"len": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.List | stack.String | stack.Bytes | stack.Map); err != nil {
+			return err
+		}
 
 		if seq := st.Pop(); seq.Is(stack.String) {
-			st.Push(stack.NewNumberValue(float32(len(seq.GetString()))))
+			st.Push(stack.NewIntValue(int64(len(seq.GetString()))))
+		} else if seq.Is(stack.Bytes) {
+			st.Push(stack.NewIntValue(int64(len(seq.GetBytes()))))
+		} else if seq.Is(stack.Map) {
+			st.Push(stack.NewIntValue(int64(seq.GetMap().Len())))
 		} else {
-			st.Push(stack.NewNumberValue(float32(len(seq.GetList()))))
+			st.Push(stack.NewIntValue(int64(len(seq.GetList()))))
 		}
 
 		return nil
 	},
-	"index": func(st *stack.Stack) error {
-		st.Expect(stack.List|stack.String, stack.Number)
+	"index": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.List|stack.String|stack.Bytes, stack.Int); err != nil {
+			return err
+		}
 
-		if i, seq := st.Pop(), st.Pop(); seq.Is(stack.String) {
-			st.Push(stack.NewNumberValue(float32(seq.GetString()[int(i.GetNum())])))
+		i, seq := st.Pop().GetInt(), st.Pop()
+		if seq.Is(stack.String) {
+			if i < 0 || i >= int64(len(seq.GetString())) {
+				return fmt.Errorf("index %d is out of range of a string of length %d", i, len(seq.GetString()))
+			}
+			st.Push(stack.NewIntValue(int64(seq.GetString()[i])))
+		} else if seq.Is(stack.Bytes) {
+			if i < 0 || i >= int64(len(seq.GetBytes())) {
+				return fmt.Errorf("index %d is out of range of bytes of length %d", i, len(seq.GetBytes()))
+			}
+			st.Push(stack.NewIntValue(int64(seq.GetBytes()[i])))
 		} else {
-			st.Push(seq.GetList()[int(i.GetNum())])
+			if i < 0 || i >= int64(len(seq.GetList())) {
+				return fmt.Errorf("index %d is out of range of a list of length %d", i, len(seq.GetList()))
+			}
+			st.Push(seq.GetList()[i])
+		}
+
+		return nil
+	},
+	"append": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.List, stack.Any); err != nil {
+			return err
+		}
+
+		item, lv := st.Pop(), st.Pop()
+		if item.Contains(lv) {
+			return errors.New("a list cannot contain itself")
+		} else if err := vm.CheckListLength(len(lv.GetList()) + 1); err != nil {
+			return err
+		}
+
+		lv.SetList(append(lv.GetList(), item))
+		st.Push(lv)
+		return nil
+	},
+	"setIndex": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.List, stack.Int, stack.Any); err != nil {
+			return err
+		}
+
+		item, i, lv := st.Pop(), st.Pop().GetInt(), st.Pop()
+		if i < 0 || i >= int64(len(lv.GetList())) {
+			return fmt.Errorf("index %d is out of range of a list of length %d", i, len(lv.GetList()))
+		} else if item.Contains(lv) {
+			return errors.New("a list cannot contain itself")
+		}
+
+		lv.GetList()[i] = item
+		st.Push(lv)
+		return nil
+	},
+	"insert": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.List, stack.Int, stack.Any); err != nil {
+			return err
+		}
+
+		// inserting at the length of the list is the same as appending
+		item, i, lv := st.Pop(), st.Pop().GetInt(), st.Pop()
+		if i < 0 || i > int64(len(lv.GetList())) {
+			return fmt.Errorf("index %d is out of range of a list of length %d", i, len(lv.GetList()))
+		} else if item.Contains(lv) {
+			return errors.New("a list cannot contain itself")
+		} else if err := vm.CheckListLength(len(lv.GetList()) + 1); err != nil {
+			return err
+		}
+
+		lv.SetList(slices.Insert(lv.GetList(), int(i), item))
+		st.Push(lv)
+		return nil
+	},
+	"remove": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.List, stack.Int); err != nil {
+			return err
+		}
+
+		i, lv := st.Pop().GetInt(), st.Pop()
+		if i < 0 || i >= int64(len(lv.GetList())) {
+			return fmt.Errorf("index %d is out of range of a list of length %d", i, len(lv.GetList()))
+		}
+
+		lv.SetList(slices.Delete(lv.GetList(), int(i), int(i)+1))
+		st.Push(lv)
+		return nil
+	},
+	"slice": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.List, stack.Int, stack.Int); err != nil {
+			return err
+		}
+
+		end, start, items := st.Pop().GetInt(), st.Pop().GetInt(), st.Pop().GetList()
+		if start < 0 || end < start || end > int64(len(items)) {
+			return fmt.Errorf("slice %d:%d is out of range of a list of length %d", start, end, len(items))
+		}
+
+		st.Push(stack.NewListValue(slices.Clone(items[start:end])...))
+		return nil
+	},
+	"concat": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.List|stack.String, stack.List|stack.String); err != nil {
+			return err
 		}
 
+		// two strings are joined into a string, otherwise both have to be lists
+		if kind := (*st)[len(*st)-2].GetKind(); kind == stack.String {
+			if err := st.Expect(stack.String, stack.String); err != nil {
+				return err
+			}
+
+			y, x := st.Pop().GetString(), st.Pop().GetString()
+			if err := vm.CheckStringBytes(len(x) + len(y)); err != nil {
+				return err
+			}
+
+			st.Push(stack.NewStringValue(x + y))
+			return nil
+		} else if err := st.Expect(stack.List, stack.List); err != nil {
+			return err
+		}
+
+		y, x := st.Pop().GetList(), st.Pop().GetList()
+		if err := vm.CheckListLength(len(x) + len(y)); err != nil {
+			return err
+		}
+
+		st.Push(stack.NewListValue(slices.Concat(x, y)...))
+		return nil
+	},
+	"reverse": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.List); err != nil {
+			return err
+		}
+
+		reversed := slices.Clone(st.Pop().GetList())
+		slices.Reverse(reversed)
+		st.Push(stack.NewListValue(reversed...))
+		return nil
+	},
+	"contains": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.List, stack.Any); err != nil {
+			return err
+		}
+
+		item, items := st.Pop(), st.Pop().GetList()
+		st.Push(stack.NewBoolValue(slices.ContainsFunc(items, item.Equals)))
+		return nil
+	},
+	"indexOf": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.List, stack.Any); err != nil {
+			return err
+		}
+
+		// items that aren't in the list have an index of -1
+		item, items := st.Pop(), st.Pop().GetList()
+		st.Push(stack.NewIntValue(int64(slices.IndexFunc(items, item.Equals))))
+		return nil
+	},
+	"map": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.List, stack.Function); err != nil {
+			return err
+		}
+
+		fn, items := st.Pop().GetFunc(), st.Pop().GetList()
+		mapped := make([]stack.StackValue, len(items))
+		for i, item := range items {
+			if v, err := callFunc(st, fn, stack.Any, item); err != nil {
+				return err
+			} else {
+				mapped[i] = v
+			}
+		}
+
+		st.Push(stack.NewListValue(mapped...))
+		return nil
+	},
+	"sort": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.List, stack.Function); err != nil {
+			return err
+		}
+
+		fn, sorted := st.Pop().GetFunc(), slices.Clone(st.Pop().GetList())
+
+		var err error
+		sort.SliceStable(sorted, func(i, j int) bool {
+			if err != nil {
+				return false
+			}
+
+			var less stack.StackValue
+			less, err = callFunc(st, fn, stack.Bool, sorted[i], sorted[j])
+			return less.GetBool()
+		})
+		if err != nil {
+			return err
+		}
+
+		st.Push(stack.NewListValue(sorted...))
+		return nil
+	},
+	"unique": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.List); err != nil {
+			return err
+		}
+
+		// values with the same hash are only the same if they're equal
+		seen := map[uint64][]stack.StackValue{}
+		unique := []stack.StackValue{}
+
+	items:
+		for _, item := range st.Pop().GetList() {
+			h := item.Hash()
+			for _, other := range seen[h] {
+				if item.Equals(other) {
+					continue items
+				}
+			}
+
+			seen[h] = append(seen[h], item)
+			unique = append(unique, item)
+		}
+
+		st.Push(stack.NewListValue(unique...))
 		return nil
 	},
 	// end seqence operations
+
+	// bytes operations
+	"bytesNew": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Int); err != nil {
+			return err
+		}
+		size := int(st.Pop().GetInt())
+		if size < 0 {
+			return errors.New("bytes size cannot be negative")
+		} else if err := vm.CheckStringBytes(size); err != nil {
+			return err
+		}
+
+		st.Push(stack.NewBytesValue(make([]byte, size)))
+		return nil
+	},
+	"bytesSet": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Bytes, stack.Int, stack.Int); err != nil {
+			return err
+		}
+
+		b, i, bv := st.Pop().GetInt(), st.Pop().GetInt(), st.Pop()
+		if i < 0 || i >= int64(len(bv.GetBytes())) {
+			return fmt.Errorf("index %d is out of range of bytes of length %d", i, len(bv.GetBytes()))
+		} else if b < 0 || b > 255 {
+			return fmt.Errorf("%d doesn't fit in a byte", b)
+		}
+
+		bv.GetBytes()[i] = byte(b)
+		st.Push(bv)
+		return nil
+	},
+	"bytesSlice": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Bytes, stack.Int, stack.Int); err != nil {
+			return err
+		}
+
+		end, start, b := st.Pop().GetInt(), st.Pop().GetInt(), st.Pop().GetBytes()
+		if start < 0 || end < start || end > int64(len(b)) {
+			return fmt.Errorf("slice %d:%d is out of range of bytes of length %d", start, end, len(b))
+		}
+
+		// the slice is a copy, so setting its bytes doesn't change the original
+		st.Push(stack.NewBytesValue(slices.Clone(b[start:end])))
+		return nil
+	},
+	"bytesConcat": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Bytes, stack.Bytes); err != nil {
+			return err
+		}
+
+		y, x := st.Pop().GetBytes(), st.Pop().GetBytes()
+		if err := vm.CheckStringBytes(len(x) + len(y)); err != nil {
+			return err
+		}
+
+		st.Push(stack.NewBytesValue(slices.Concat(x, y)))
+		return nil
+	},
+	"toBytes": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.String); err != nil {
+			return err
+		}
+		st.Push(stack.NewBytesValue([]byte(st.Pop().GetString())))
+		return nil
+	},
+	"bytesToString": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Bytes); err != nil {
+			return err
+		}
+		st.Push(stack.NewStringValue(string(st.Pop().GetBytes())))
+		return nil
+	},
+	// end bytes operations
+
+	// map operations
+	"mapNew": func(vm *VelvetVM, st *stack.Stack) error {
+		st.Push(stack.NewMapValue(stack.NewValueMap()))
+		return nil
+	},
+	"mapGet": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Map, stack.Key); err != nil {
+			return err
+		}
+
+		// keys that aren't in the map get null
+		key, m := st.Pop(), st.Pop().GetMap()
+		value, _ := m.Get(key)
+		st.Push(value)
+		return nil
+	},
+	"mapSet": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Map, stack.Key, stack.Any); err != nil {
+			return err
+		}
+
+		value, key, mv := st.Pop(), st.Pop(), st.Pop()
+		if value.Contains(mv) {
+			return errors.New("a map cannot contain itself")
+		} else if m := mv.GetMap(); !m.Has(key) {
+			if err := vm.CheckListLength(m.Len() + 1); err != nil {
+				return err
+			}
+		}
+
+		mv.GetMap().Set(key, value)
+		st.Push(mv)
+		return nil
+	},
+	"mapHas": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Map, stack.Key); err != nil {
+			return err
+		}
+
+		key, m := st.Pop(), st.Pop().GetMap()
+		st.Push(stack.NewBoolValue(m.Has(key)))
+		return nil
+	},
+	"mapDelete": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Map, stack.Key); err != nil {
+			return err
+		}
+
+		key, mv := st.Pop(), st.Pop()
+		mv.GetMap().Delete(key)
+		st.Push(mv)
+		return nil
+	},
+	"mapKeys": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Map); err != nil {
+			return err
+		}
+		st.Push(stack.NewListValue(st.Pop().GetMap().Keys()...))
+		return nil
+	},
+	"mapLen": func(vm *VelvetVM, st *stack.Stack) error {
+		if err := st.Expect(stack.Map); err != nil {
+			return err
+		}
+		st.Push(stack.NewIntValue(int64(st.Pop().GetMap().Len())))
+		return nil
+	},
+	// end map operations
 }
@@ -0,0 +1,88 @@
+package vm
+
+import "testing"
+
+// Runs source that's expected to leave the error flag set after its last call, returning the error message
+func runRejected(t *testing.T, source string) string {
+	t.Helper()
+
+	out, res, err := run(t, source+"\njne accepted\npusherr\ncall println\nhalt 0\n.accepted\n  halt 1")
+	if err != nil {
+		t.Fatal(err)
+	} else if res.ExitCode != 0 {
+		t.Fatal("expected the error flag to be set")
+	}
+	return out
+}
+
+func TestMapSetRejectsItself(t *testing.T) {
+	tests := []struct {
+		name, source string
+	}{
+		{"value", "call mapNew\ndup\npush \"self\"\nswap\ncall mapSet"},
+		{"inside a list", "@vars 1\ncall mapNew\nset 0\nget 0\npush \"self\"\npush []\nget 0\ncall append\ncall mapSet"},
+		{"inside a map", "@vars 1\ncall mapNew\nset 0\nget 0\npush \"self\"\ncall mapNew\npush 1\nget 0\ncall mapSet\ncall mapSet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if out := runRejected(t, tt.source); out != "a map cannot contain itself\n" {
+				t.Errorf("expected the map to be rejected, but got %q", out)
+			}
+		})
+	}
+}
+
+func TestMapSetSharedValue(t *testing.T) {
+	// the same list can be in a map more than once, as long as the map isn't in it
+	out, _, err := run(t, `
+@vars 1
+push [1]
+set 0
+call mapNew
+push "a"
+get 0
+call mapSet
+push "b"
+get 0
+call mapSet
+call println
+halt 0
+`)
+
+	if err != nil {
+		t.Fatal(err)
+	} else if out != "{ \"a\" [ 1 ] \"b\" [ 1 ] }\n" {
+		t.Errorf("expected both keys to be set, but got %q", out)
+	}
+}
+
+func TestListRejectsItself(t *testing.T) {
+	tests := []struct {
+		name, source string
+	}{
+		{"append", "push []\ndup\ncall append"},
+		{"setIndex", "push [0]\ndup\npush 0\nswap\ncall setIndex"},
+		{"insert", "push []\ndup\npush 0\nswap\ncall insert"},
+		{"inside a list", "@vars 1\npush []\nset 0\nget 0\npush []\nget 0\ncall append\ncall append"},
+		{"inside a map", "@vars 1\npush []\nset 0\nget 0\ncall mapNew\npush 1\nget 0\ncall mapSet\ncall append"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if out := runRejected(t, tt.source); out != "a list cannot contain itself\n" {
+				t.Errorf("expected the list to be rejected, but got %q", out)
+			}
+		})
+	}
+}
+
+func TestAppendedListCanBePrinted(t *testing.T) {
+	// append doesn't return anything when it fails, so the list is printed from a copy that was left under it
+	out, _, err := run(t, "push []\ndup\ndup\ncall append\ncall println\nhalt 0")
+	if err != nil {
+		t.Fatal(err)
+	} else if out != "[  ]\n" {
+		t.Errorf("expected an empty list, but got %q", out)
+	}
+}
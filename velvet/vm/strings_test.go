@@ -0,0 +1,81 @@
+package vm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/voidwyrm-2/velvet-vm/velvet/vm/stack"
+)
+
+func TestStringFunctions(t *testing.T) {
+	tests := []struct {
+		name, source, expected string
+	}{
+		{"concat", "push \"ab\"\npush \"cd\"\ncall concat", "abcd"},
+		{"concat lists", "push [1]\npush [2]\ncall concat", "[ 1 2 ]"},
+		{"substr", "push \"hello\"\npush 1\npush 3\ncall substr", "el"},
+		{"find", "push \"hello\"\npush \"l\"\ncall find", "2"},
+		{"find missing", "push \"hello\"\npush \"z\"\ncall find", "-1"},
+		{"replace", "push \"a-b-c\"\npush \"-\"\npush \"+\"\ncall replace", "a+b+c"},
+		{"upper", "push \"Hi\"\ncall upper", "HI"},
+		{"lower", "push \"Hi\"\ncall lower", "hi"},
+		{"startsWith", "push \"hello\"\npush \"he\"\ncall startsWith", "true"},
+		{"endsWith", "push \"hello\"\npush \"he\"\ncall endsWith", "false"},
+		{"join", "push [\"a\" \"b\" \"c\"]\npush \", \"\ncall join", "a, b, c"},
+		{"repeat", "push \"ab\"\npush 3\ncall repeat", "ababab"},
+		{"repeat zero", "push \"ab\"\npush 0\ncall repeat", ""},
+		{"chars", "push \"abc\"\ncall chars", "[ \"a\" \"b\" \"c\" ]"},
+		{"toString", "push [1 \"a\" true null]\ncall toString", "[ 1 \"a\" true null ]"},
+		{"toString is a string", "push 1.5\ncall toString\ncall typeof", "String"},
+		{"parseNumber", "push \"-12\"\ncall parseNumber\npush 2\nmul", "-24"},
+		{"split", "push \"a,b\"\npush \",\"\ncall split", "[ \"a\" \"b\" ]"},
+		{"strip", "push \"  a b \"\ncall strip", "a b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, _, err := run(t, tt.source+"\ncall println\nhalt 0")
+			if err != nil {
+				t.Fatal(err)
+			} else if out != tt.expected+"\n" {
+				t.Errorf("expected %q, but got %q", tt.expected+"\n", out)
+			}
+		})
+	}
+}
+
+func TestStringFunctionErrors(t *testing.T) {
+	tests := []struct {
+		name, source, expected string
+	}{
+		{"substr out of range", "push \"abc\"\npush 1\npush 4\ncall substr", "substring 1:4 is out of range of a string of length 3"},
+		{"substr backwards", "push \"abc\"\npush 2\npush 1\ncall substr", "substring 2:1 is out of range of a string of length 3"},
+		{"join non-strings", "push [\"a\" 1]\npush \",\"\ncall join", "item 1 of the list is a Int, not a String"},
+		{"repeat negative", "push \"a\"\npush -1\ncall repeat", "cannot repeat a string a negative amount of times"},
+		{"repeat overflow", "push \"ab\"\npush 9223372036854775807\ncall repeat", "the repeated string would be too large"},
+		{"parseNumber", "push \"12abc\"\ncall parseNumber", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := runRejected(t, tt.source)
+			if tt.expected == "" && out == "\n" {
+				t.Error("expected an error message")
+			} else if tt.expected != "" && out != tt.expected+"\n" {
+				t.Errorf("expected %q, but got %q", tt.expected+"\n", out)
+			}
+		})
+	}
+}
+
+func TestConcatMixedKinds(t *testing.T) {
+	// a string and a list can't be joined, and the wrong kind stops the program
+	_, _, err := run(t, "push \"a\"\npush [1]\ncall concat\nhalt 0")
+
+	var re *RuntimeError
+	if !errors.As(err, &re) {
+		t.Fatalf("expected a *RuntimeError, but got %v", err)
+	} else if re.Function != "concat" || re.Expected != stack.String || re.Actual != stack.List {
+		t.Errorf("expected concat to want a String but find a List, but got %v", err)
+	}
+}
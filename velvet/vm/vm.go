@@ -1,383 +1,408 @@
 package vm
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"sync/atomic"
 
 	"github.com/voidwyrm-2/velvet-vm/velvet/vm/stack"
 )
 
-const InstructionSize int = 7
-
-func getInstruction(bytes []uint8, pc int) (uint16, struct {
-	flags [8]bool
-	num   uint8
-}, struct {
-	one, two uint16
-	both     uint32
-},
-) {
-	instruction := bytes[pc : pc+7]
-	flags := [8]bool{}
-
-	for i := range 8 {
-		if (instruction[2]>>i)&1 == 1 {
-			flags[i] = true
-		} else {
-			flags[i] = false
-		}
+// Result holds the state of the VM after a program has halted
+type Result struct {
+	ExitCode     int
+	Stack        stack.Stack
+	Vars         []stack.StackValue
+	Instructions int
+}
+
+type VelvetVM struct {
+	stack                       stack.Stack
+	callables                   map[string]func(st *stack.Stack) error
+	input                       *ctxReader
+	stdin                       *bufio.Reader
+	stdout, stderr, diagnostics io.Writer
+	errFlag                     bool
+	errReg                      string
+	fuel, fuelBudget            int64
+	limits                      Limits
+	running                     atomic.Bool
+	current                     *execution
+}
+
+// Option configures a VelvetVM when it's created with New
+type Option func(vm *VelvetVM)
+
+// Sets the reader that the read functions take input from, os.Stdin by default
+func WithStdin(r io.Reader) Option {
+	return func(vm *VelvetVM) {
+		vm.input = newCtxReader(r)
 	}
+}
 
-	args := struct {
-		one  uint16
-		two  uint16
-		both uint32
-	}{
-		one: uint16(instruction[3])<<8 + uint16(instruction[4]),
-		two: uint16(instruction[5])<<8 + uint16(instruction[6]),
+// Sets the writer that the print functions write to, os.Stdout by default
+func WithStdout(w io.Writer) Option {
+	return func(vm *VelvetVM) {
+		vm.stdout = w
 	}
-	args.both = uint32(args.one)<<16 + uint32(args.two)
-
-	return (uint16(instruction[0]) << 8) + uint16(instruction[1]), struct {
-		flags [8]bool
-		num   uint8
-	}{
-		flags: flags, num: instruction[2],
-	}, args
 }
 
-func initDataGetter(bytes []byte, dataAddr int) (func(addr uint16, length uint) ([]byte, error), error) {
-	bytesGetter := func(addr uint16, length uint) ([]byte, error) {
-		if dataAddr+int(addr)+int(length) > len(bytes) {
-			return []byte{}, fmt.Errorf("data section address '%d' is not valid", dataAddr)
-		}
-		return bytes[dataAddr+int(addr) : dataAddr+int(addr)+int(length)], nil
+// Sets the writer that the eprint functions write to, os.Stderr by default
+func WithStderr(w io.Writer) Option {
+	return func(vm *VelvetVM) {
+		vm.stderr = w
 	}
+}
 
-	/*
-		stringGetter := func(addr uint16, length uint) (string, error) {
-			b, e := bytesGetter(addr, length)
-			return string(b), e
-		}
-	*/
+// Sets the writer that stack and variable dumps are written to, os.Stderr by default
+func WithDiagnostics(w io.Writer) Option {
+	return func(vm *VelvetVM) {
+		vm.diagnostics = w
+	}
+}
 
-	if int(dataAddr) >= len(bytes) {
-		return bytesGetter, fmt.Errorf("data section address '%d' is not valid", dataAddr)
+/*
+Limits how many instructions a single run can execute, each instruction costs one fuel and functions can charge more with Charge;
+a budget of zero or less means there's no limit, which is the default
+*/
+func WithFuel(budget int64) Option {
+	return func(vm *VelvetVM) {
+		vm.fuelBudget = budget
 	}
-	return bytesGetter, nil
 }
 
-func makeListFromBytes(lb []byte, getBytes func(addr uint16, length uint) ([]byte, error)) ([]stack.StackValue, error) {
-	if len(lb) == 0 {
-		return []stack.StackValue{}, nil
+/*
+Creates a VM, a VM runs one program at a time, so programs that run at the same time each need their own VM;
+VMs are cheap to create and a loaded Program can be shared between them
+*/
+func New(opts ...Option) *VelvetVM {
+	vm := &VelvetVM{
+		stack:       stack.New(),
+		callables:   map[string]func(st *stack.Stack) error{},
+		input:       newCtxReader(os.Stdin),
+		stdout:      os.Stdout,
+		stderr:      os.Stderr,
+		diagnostics: os.Stderr,
 	}
 
-	itemBytes := []struct {
-		kind         uint8
-		addr, length uint16
-	}{}
-
-	for i := 0; i < len(lb); i += 5 {
-		itemBytes = append(itemBytes, struct {
-			kind   uint8
-			addr   uint16
-			length uint16
-		}{
-			kind:   lb[i],
-			addr:   (uint16(lb[i+1]) << 8) + uint16(lb[i+2]),
-			length: (uint16(lb[i+3]) << 8) + uint16(lb[i+4]),
-		})
+	for _, opt := range opts {
+		opt(vm)
 	}
 
-	items := []stack.StackValue{}
+	return vm
+}
 
-	for _, it := range itemBytes {
-		switch it.kind {
-		case stack.String: // string
-			if str, err := getBytes(it.addr, uint(it.length)); err != nil {
-				return []stack.StackValue{}, err
-			} else {
-				items = append(items, stack.NewStringValue(string(str)))
-			}
-		case stack.Bool: // bool
-			if b, err := getBytes(it.addr, 1); err != nil {
-				return []stack.StackValue{}, err
-			} else {
-				items = append(items, stack.NewBoolValue(b[0] == 1))
-			}
-		case stack.List: // list
-			if sublsb, err := getBytes(it.addr, uint(it.length)*5); err != nil {
-				return []stack.StackValue{}, err
-			} else if subls, err := makeListFromBytes(sublsb, getBytes); err != nil {
-				return []stack.StackValue{}, err
-			} else {
-				items = append(items, stack.NewListValue(subls...))
-			}
-		/*case stack.Function:
-		if fnName, err := getBytes(args.one, uint(args.two)); err != nil {
-			return []stack.StackValue, err
-		} else if fn, ok := vm.callables[string(fnName)]; !ok {
-			return []stack.StackValue,fmt.Errorf("function '%s' does not exist", string(fnName))
-		} else {
-			vm.stack.Push(stack.NewFuncValue(fn))
-		}*/
-		default: // number
-			if b, err := getBytes(it.addr, 4); err != nil {
-				return []stack.StackValue{}, err
-			} else {
-				items = append(items, stack.NewNumberValue(float32(int((uint(b[0])<<24)+(uint(b[1])<<16)+(uint(b[2])<<8)+uint(b[3])))))
-			}
-		}
+// Returns the buffered reader over the VM's input, creating it on the first read
+func (vm *VelvetVM) reader() *bufio.Reader {
+	if vm.stdin == nil {
+		vm.stdin = bufio.NewReader(vm.input)
 	}
-
-	return items, nil
+	return vm.stdin
 }
 
-type VelvetVM struct {
-	stack     stack.Stack
-	callables map[string]func(st *stack.Stack) error
+func (vm *VelvetVM) DumpStack() string {
+	return vm.stack.Dump()
 }
 
-func New() VelvetVM {
-	return VelvetVM{
-		stack:     stack.New(),
-		callables: stdfn,
-	}
+// how many instructions are executed between checks of the context passed to RunContext
+const contextCheckInterval = 1024
+
+func (vm *VelvetVM) Run(bytes []byte, dumpStackAfterEachInstruction, dumpVarsAfterEachInstruction bool) (Result, error) {
+	return vm.RunContext(context.Background(), bytes, dumpStackAfterEachInstruction, dumpVarsAfterEachInstruction)
 }
 
-func (vm VelvetVM) DumpStack() string {
-	return vm.stack.Dump()
+/*
+Runs the program like Run, but stops it once the given context is done, including while a read function is waiting for input;
+the returned error is then a *RuntimeError wrapping the context's error
+*/
+func (vm *VelvetVM) RunContext(ctx context.Context, bytes []byte, dumpStackAfterEachInstruction, dumpVarsAfterEachInstruction bool) (Result, error) {
+	prog, err := Load(bytes)
+	if err != nil {
+		return Result{}, err
+	}
+	return vm.RunProgram(ctx, prog, dumpStackAfterEachInstruction, dumpVarsAfterEachInstruction)
 }
 
-func (vm VelvetVM) VerifyBytecode(bytes []byte) (struct {
-	isLibrary, f2, f3, f4, f5, f6, f7, f8 bool
-}, int, int, int, bool,
-) {
-	if len(bytes) < 32 {
-		return struct {
-			isLibrary bool
-			f2        bool
-			f3        bool
-			f4        bool
-			f5        bool
-			f6        bool
-			f7        bool
-			f8        bool
-		}{}, 0, 0, 0, false
-	} else if !strings.HasPrefix(string(bytes), "Velvet Scarlatina") {
-		return struct {
-			isLibrary bool
-			f2        bool
-			f3        bool
-			f4        bool
-			f5        bool
-			f6        bool
-			f7        bool
-			f8        bool
-		}{}, 0, 0, 0, false
+/*
+Runs a program that has already been loaded with Load, stopping it once the given context is done
+*/
+func (vm *VelvetVM) RunProgram(ctx context.Context, prog *Program, dumpStackAfterEachInstruction, dumpVarsAfterEachInstruction bool) (Result, error) {
+	if prog.isLibrary {
+		return Result{}, errors.New("this Velvet bytecode executable has been declared as a library meaning it cannot be directly run, it must be imported by a non-library Velvet executable")
+	} else if !vm.running.CompareAndSwap(false, true) {
+		return Result{}, errors.New("this VM is already running a program, each program running at the same time needs its own VM")
 	}
+	defer vm.running.Store(false)
 
-	return struct {
-		isLibrary bool
-		f2        bool
-		f3        bool
-		f4        bool
-		f5        bool
-		f6        bool
-		f7        bool
-		f8        bool
-	}{isLibrary: (bytes[17] >> 7) == 1}, (int(bytes[18]) << 8) + int(bytes[19]), (int(bytes[20]) << 24) + (int(bytes[21]) << 16) + (int(bytes[22]) << 8) + int(bytes[23]), (int(bytes[24]) << 24) + (int(bytes[25]) << 16) + (int(bytes[26]) << 8) + int(bytes[27]), true
-}
+	fns, err := vm.link(prog)
+	if err != nil {
+		return Result{}, err
+	}
 
-func (vm VelvetVM) Run(bytes []byte, dumpStackAfterEachInstruction, dumpVarsAfterEachInstruction bool) error {
-	var (
-		flags struct {
-			isLibrary, f2, f3, f4, f5, f6, f7, f8 bool
-		}
-		vars                  []stack.StackValue
-		dataAddr, entryOffset int
-		errFlag               bool
-		errReg                string
-	)
-
-	setErr := func(e error) {
-		if e != nil {
-			errFlag = true
-			errReg = e.Error()
-		}
+	vm.stack = stack.New()
+	vm.errFlag, vm.errReg = false, ""
+	vm.fuel = vm.fuelBudget
+
+	vm.input.ctx = ctx
+	defer func() {
+		vm.input.ctx = context.Background()
+	}()
+
+	ex := &execution{
+		prog:      prog,
+		fns:       fns,
+		ctx:       ctx,
+		vars:      make([]stack.StackValue, prog.vars),
+		callstack: []frame{},
+		locals:    []stack.StackValue{},
+		dumpStack: dumpStackAfterEachInstruction,
+		dumpVars:  dumpVarsAfterEachInstruction,
+
+		funcValues:    make([]stack.StackValue, len(fns)),
+		bytecodeFuncs: map[int]stack.StackValue{},
 	}
 
-	if _flags, _vars, _dataAddr, _entryOffset, ok := vm.VerifyBytecode(bytes); !ok {
-		return errors.New("malformed bytecode format")
-	} else {
-		flags, vars, dataAddr, entryOffset = _flags, make([]stack.StackValue, _vars), _dataAddr, _entryOffset
+	for i := range ex.vars {
+		ex.vars[i] = stack.NewNullValue()
 	}
 
-	if entryOffset > dataAddr {
-		return fmt.Errorf("the start of execution is inside the data section")
+	vm.current = ex
+	defer func() {
+		vm.current = nil
+	}()
+
+	if err := vm.exec(ex, prog.entry, -1); ex.halted == nil {
+		return Result{}, err
 	}
+	return *ex.halted, nil
+}
 
-	getBytes, err := initDataGetter(bytes, dataAddr)
+func (vm *VelvetVM) setErr(err error) {
 	if err != nil {
-		return err
+		vm.errFlag = true
+		vm.errReg = err.Error()
 	}
+}
 
-	if flags.isLibrary {
-		return errors.New("this Velvet bytecode executable has been declared as a library meaning it cannot be directly run, it must be imported by a non-library Velvet executable")
+// wraps an error that stops the program, errors from bytecode that a function called back into have already been wrapped
+func (vm *VelvetVM) fail(at int, opcode uint16, function string, err error) error {
+	var re *RuntimeError
+	if errors.Is(err, errHalted) {
+		return err
+	} else if errors.As(err, &re) {
+		return re
 	}
+	return newRuntimeError(addrOf(at), opcode, function, vm.stack, err)
+}
+
+/*
+Executes instructions starting at pc until the program halts, which returns errHalted,
+or until ret removes the frame at index base of the call stack, which returns nil
+*/
+func (vm *VelvetVM) exec(ex *execution, pc, base int) error {
+	prog := ex.prog
 
-	callstack := []int{}
+	// the last instruction that was run, which is where the error is reported if execution runs off the end
+	at := max(pc-1, 0)
 
-	pc := 32 + (entryOffset * 7)
 	for {
-		if pc+7 >= len(bytes) {
-			return errors.New("end of bytes reached")
+		if pc >= len(prog.instructions) {
+			return newRuntimeError(addrOf(at), prog.instructions[at].opcode, "", vm.stack, errors.New("end of instructions reached"))
 		}
 
-		opcode, fb, args := getInstruction(bytes, pc)
+		at = pc
+		ins := &prog.instructions[at]
 
-		switch opcode {
+		if err := vm.Charge(1); err != nil {
+			return vm.fail(at, ins.opcode, "", err)
+		} else if ex.executed%contextCheckInterval == 0 && ex.ctx.Err() != nil {
+			return vm.fail(at, ins.opcode, "", ex.ctx.Err())
+		}
+		ex.executed++
+		pc++
+
+		switch ins.opcode {
 		case 0: // nop
-			pc += InstructionSize
 		case 1: // ret
-			if len(callstack) > 0 {
-				addr := callstack[len(callstack)-1]
-				callstack = callstack[:len(callstack)-1]
-				pc = addr
-			} else {
-				pc += InstructionSize
+			if len(ex.callstack) > 0 {
+				f := ex.callstack[len(ex.callstack)-1]
+				ex.callstack = ex.callstack[:len(ex.callstack)-1]
+
+				clear(ex.locals[f.base:])
+				ex.locals = ex.locals[:f.base]
+
+				if len(ex.callstack) == base {
+					return nil
+				}
+				pc = f.ret
 			}
 		case 2: // halt
-			os.Exit(int(args.one))
-			pc += InstructionSize
+			ex.halted = &Result{ExitCode: int(int8(ins.one)), Stack: vm.stack, Vars: ex.vars, Instructions: ex.executed}
+			return errHalted
 		case 3: // call
-			if fb.flags[0] {
-				vm.stack.Expect(stack.Function)
-				setErr(vm.stack.Pop().GetFunc()(&vm.stack))
-			} else {
-				if fnName, err := getBytes(args.one, uint(args.two)); err != nil {
-					return err
-				} else if string(fnName) == "getErr" {
-					vm.stack.Push(stack.NewStringValue(errReg))
-				} else if fn, ok := vm.callables[string(fnName)]; !ok {
-					return fmt.Errorf("function '%s' does not exist", string(fnName))
+			if ins.flag&1 == 1 {
+				if err := vm.stack.Expect(stack.Function); err != nil {
+					return vm.fail(at, ins.opcode, "", err)
+				}
+
+				fn := vm.stack.Pop()
+				if addr, ok := fn.GetBytecodeAddr(ex); ok {
+					// bytecode functions of this program are branched to instead of being called from Go
+					if err := vm.checkCallDepth(len(ex.callstack) + 1); err != nil {
+						return vm.fail(at, ins.opcode, "", err)
+					}
+					ex.callstack = append(ex.callstack, frame{ret: pc, base: len(ex.locals)})
+					pc, _ = indexOf(addr)
+				} else if err := fn.GetFunc()(&vm.stack); ex.halted != nil {
+					return errHalted
+				} else if isFatal(err) {
+					return vm.fail(at, ins.opcode, "", err)
 				} else {
-					setErr(fn(&vm.stack))
+					vm.setErr(err)
 				}
+			} else if ins.fn < 0 {
+				vm.stack.Push(stack.NewStringValue(vm.errReg))
+			} else if err := ex.fns[ins.fn](&vm.stack); ex.halted != nil {
+				return errHalted
+			} else if isFatal(err) {
+				return vm.fail(at, ins.opcode, prog.imports[ins.fn], err)
+			} else {
+				vm.setErr(err)
 			}
-			pc += InstructionSize
 		case 4: // push
-			switch fb.num {
-			case 1: // bool
-				vm.stack.Push(stack.NewBoolValue(args.one != 0))
-			case 2: // string
-				if str, err := getBytes(args.one, uint(args.two)); err != nil {
-					return err
-				} else {
-					vm.stack.Push(stack.NewStringValue(string(str)))
-				}
-			case 3: // list
-				if lb, err := getBytes(args.one, uint(args.two)*5); err != nil {
-					return err
-				} else if ls, err := makeListFromBytes(lb, getBytes); err != nil {
-					return err
-				} else {
-					vm.stack.Push(stack.NewListValue(ls...))
-				}
+			switch ins.flag {
+			case 3, 10: // list, map
+				vm.stack.Push(copyConstant(ins.value))
 			case 4: // function
-				if fnName, err := getBytes(args.one, uint(args.two)); err != nil {
-					return err
-				} else if fn, ok := vm.callables[string(fnName)]; !ok {
-					return fmt.Errorf("function '%s' does not exist", string(fnName))
-				} else {
-					vm.stack.Push(stack.NewFuncValue(fn))
-				}
+				vm.stack.Push(ex.funcValue(ins.fn))
 			case 5: // error register
-				vm.stack.Push(stack.NewStringValue(errReg))
+				vm.stack.Push(stack.NewStringValue(vm.errReg))
+			case 6: // bytecode function
+				vm.stack.Push(vm.bytecodeFunc(ex, ins.target))
 			default:
-				vm.stack.Push(stack.NewNumberValue(float32(int(args.both))))
+				vm.stack.Push(ins.value)
 			}
-			pc += InstructionSize
 		case 5: // pop
-			vm.stack.Expect(stack.Any)
+			if err := vm.stack.Expect(stack.Any); err != nil {
+				return vm.fail(at, ins.opcode, "", err)
+			}
 			vm.stack.Pop()
-			pc += InstructionSize
 		case 6: // dup
-			vm.stack.Expect(stack.Any)
+			if err := vm.stack.Expect(stack.Any); err != nil {
+				return vm.fail(at, ins.opcode, "", err)
+			}
 			item := vm.stack.Pop()
 			vm.stack.Push(item)
 			vm.stack.Push(item)
-			pc += InstructionSize
 		case 7: // swap
-			vm.stack.Expect(stack.Any, stack.Any)
+			if err := vm.stack.Expect(stack.Any, stack.Any); err != nil {
+				return vm.fail(at, ins.opcode, "", err)
+			}
 			x, y := vm.stack.Pop(), vm.stack.Pop()
-			vm.stack.Push(y)
 			vm.stack.Push(x)
-			pc += InstructionSize
+			vm.stack.Push(y)
 		case 8: // rot
-			vm.stack.Expect(stack.Any, stack.Any, stack.Any)
+			if err := vm.stack.Expect(stack.Any, stack.Any, stack.Any); err != nil {
+				return vm.fail(at, ins.opcode, "", err)
+			}
 			x, y, z := vm.stack.Pop(), vm.stack.Pop(), vm.stack.Pop()
-			vm.stack.Push(z)
-			vm.stack.Push(y)
 			vm.stack.Push(x)
-			pc += InstructionSize
+			vm.stack.Push(y)
+			vm.stack.Push(z)
 		case 9: // set/get
-			if int(args.one) >= len(vars) {
-				return fmt.Errorf("%d is not a valid variable index", args.one)
-			} else if fb.num&1 == 1 {
-				vm.stack.Push(vars[int(args.one)])
+			if ins.flag&1 == 1 {
+				vm.stack.Push(ex.vars[ins.one])
 			} else {
-				vm.stack.Expect(stack.Any)
-				vars[int(args.one)] = vm.stack.Pop()
+				if err := vm.stack.Expect(stack.Any); err != nil {
+					return vm.fail(at, ins.opcode, "", err)
+				}
+				ex.vars[ins.one] = vm.stack.Pop()
 			}
-			pc += InstructionSize
 		case 10: // j/jt/jf/je/jne or br/brt/brf/bre/brne
 			cond := true
 
-			jumpType, isBranch := exactIsBranch(fb.num)
-
-			switch jumpType {
+			switch ins.flag {
 			case 1:
-				vm.stack.Expect(stack.Bool)
+				if err := vm.stack.Expect(stack.Bool); err != nil {
+					return vm.fail(at, ins.opcode, "", err)
+				}
 				cond = vm.stack.Pop().GetBool()
 			case 2:
-				vm.stack.Expect(stack.Bool)
+				if err := vm.stack.Expect(stack.Bool); err != nil {
+					return vm.fail(at, ins.opcode, "", err)
+				}
 				cond = !vm.stack.Pop().GetBool()
 			case 3:
-				cond = errFlag
+				cond = vm.errFlag
 			case 4:
-				cond = !errFlag
+				cond = !vm.errFlag
 			}
 
 			if cond {
-				if isBranch {
-					callstack = append(callstack, pc)
+				if ins.branch {
+					if err := vm.checkCallDepth(len(ex.callstack) + 1); err != nil {
+						return vm.fail(at, ins.opcode, "", err)
+					}
+					// return to the instruction after the branch
+					ex.callstack = append(ex.callstack, frame{ret: pc, base: len(ex.locals)})
 				}
-				pc = int(args.both)
+				pc = ins.target
+			}
+		case 28: // enter
+			// the slots are checked before they're made, the arguments are already counted on the stack
+			if err := vm.checkStackDepth(len(ex.locals) + int(ins.two)); err != nil {
+				return vm.fail(at, ins.opcode, "", err)
+			}
+
+			var err error
+			if ex.locals, err = enterFrame(ex.callstack, ex.locals, &vm.stack, int(ins.one), int(ins.two)); err != nil {
+				return vm.fail(at, ins.opcode, "", err)
+			}
+		case 29: // lset/lget
+			slots := frameLocals(ex.callstack, ex.locals)
+			if err := localSlot(slots, ins.one); err != nil {
+				return vm.fail(at, ins.opcode, "", err)
+			}
+
+			if ins.flag&1 == 1 {
+				vm.stack.Push(slots[ins.one])
+			} else {
+				if err := vm.stack.Expect(stack.Any); err != nil {
+					return vm.fail(at, ins.opcode, "", err)
+				}
+				slots[ins.one] = vm.stack.Pop()
+			}
+		default: // operators, Load makes sure that no other opcodes are left
+			if err := operators[ins.opcode-firstOperator].fn(&vm.stack); isFatal(err) {
+				return vm.fail(at, ins.opcode, "", err)
 			} else {
-				pc += InstructionSize
+				vm.setErr(err)
 			}
-		default:
-			return fmt.Errorf("invalid opcode '%d'", opcode)
 		}
 
-		if dumpStackAfterEachInstruction {
-			fmt.Println(vm.stack.Dump())
+		if err := vm.checkStackDepth(len(ex.locals)); err != nil {
+			return vm.fail(at, ins.opcode, "", err)
 		}
 
-		if dumpVarsAfterEachInstruction {
-			if dumpStackAfterEachInstruction {
-				fmt.Println("")
+		if ex.dumpStack {
+			fmt.Fprintln(vm.diagnostics, vm.stack.Dump())
+		}
+
+		if ex.dumpVars {
+			if ex.dumpStack {
+				fmt.Fprintln(vm.diagnostics, "")
 			}
 
 			fmtVars := []string{}
-			for _, v := range vars {
+			for _, v := range ex.vars {
 				fmtVars = append(fmtVars, v.Dump())
 			}
+			fmt.Fprintln(vm.diagnostics, "vars [\n"+strings.Join(fmtVars, "\n")+"\n]")
 		}
 	}
-
-	panic("unreachable")
 }
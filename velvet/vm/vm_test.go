@@ -0,0 +1,57 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSwapAndRot(t *testing.T) {
+	tests := []struct {
+		name, source, expected string
+	}{
+		{"swap", "push 1\npush 2\nswap\ncall println\ncall println\nhalt 0", "1\n2\n"},
+		{"rot", "push 1\npush 2\npush 3\nrot\ncall println\ncall println\ncall println\nhalt 0", "1\n2\n3\n"},
+		{"rot leaves the rest", "push 0\npush 1\npush 2\npush 3\nrot\npop\npop\npop\ncall println\nhalt 0", "0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, _, err := run(t, tt.source)
+			if err != nil {
+				t.Fatal(err)
+			} else if out != tt.expected {
+				t.Errorf("expected %q, but got %q", tt.expected, out)
+			}
+		})
+	}
+}
+
+func TestEndOfInstructions(t *testing.T) {
+	_, _, err := run(t, "push 1\npop")
+
+	var re *RuntimeError
+	if !errors.As(err, &re) {
+		t.Fatalf("expected a *RuntimeError, but got %v", err)
+	}
+
+	// the error is reported at the last instruction that ran, which is the pop
+	if re.PC != addrOf(1) || opcodeName(re.Opcode) != "pop" {
+		t.Errorf("expected the error to be at %d (pop), but it's at %d (%s)", addrOf(1), re.PC, opcodeName(re.Opcode))
+	}
+}
+
+func TestRunContextStopsLoop(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := New().RunContext(ctx, compile(t, ".loop\n  j loop"), false, false)
+
+	var re *RuntimeError
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the loop to be stopped by the context, but got %v", err)
+	} else if !errors.As(err, &re) {
+		t.Errorf("expected a *RuntimeError, but got %T", err)
+	}
+}